@@ -0,0 +1,20 @@
+package assertvk_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := assertvk.NewFakeClock(time.Date(2021, 11, 18, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2021, 11, 18, 12, 0, 0, 0, time.UTC), clock.Now())
+
+	clock.Advance(time.Minute * 5)
+	assert.Equal(t, time.Date(2021, 11, 18, 12, 5, 0, 0, time.UTC), clock.Now())
+
+	clock.Set(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), clock.Now())
+}
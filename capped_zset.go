@@ -39,3 +39,55 @@ func (z *CappedZSet) Card(ctx context.Context, vc valkey.Conn) (int, error) {
 func (z *CappedZSet) Members(ctx context.Context, vc valkey.Conn) ([]string, []float64, error) {
 	return StringsWithScores(valkey.DoContext(vc, ctx, "ZRANGE", z.key, 0, -1, "WITHSCORES"))
 }
+
+// Remove removes the given members from the set
+func (z *CappedZSet) Remove(ctx context.Context, vc valkey.Conn, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := valkey.DoContext(vc, ctx, "ZREM", valkey.Args{}.Add(z.key).AddFlat(members)...)
+	return err
+}
+
+// Score returns the score of the given member and whether it is a member
+func (z *CappedZSet) Score(ctx context.Context, vc valkey.Conn, member string) (float64, bool, error) {
+	score, err := valkey.Float64(valkey.DoContext(vc, ctx, "ZSCORE", z.key, member))
+	if err == valkey.ErrNil {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+	return score, true, nil
+}
+
+// Rank returns the rank of the given member ordered by ascending score, or -1 if it isn't a member
+func (z *CappedZSet) Rank(ctx context.Context, vc valkey.Conn, member string) (int, error) {
+	return z.rank(ctx, vc, "ZRANK", member)
+}
+
+// RevRank returns the rank of the given member ordered by descending score, or -1 if it isn't a member
+func (z *CappedZSet) RevRank(ctx context.Context, vc valkey.Conn, member string) (int, error) {
+	return z.rank(ctx, vc, "ZREVRANK", member)
+}
+
+// Top returns the n members with the highest scores, ordered by descending score
+func (z *CappedZSet) Top(ctx context.Context, vc valkey.Conn, n int) ([]string, []float64, error) {
+	if n <= 0 {
+		return []string{}, []float64{}, nil
+	}
+	return StringsWithScores(valkey.DoContext(vc, ctx, "ZRANGE", z.key, 0, n-1, "REV", "WITHSCORES"))
+}
+
+// RangeByScore returns the members with scores between min and max inclusive, ordered by ascending score
+func (z *CappedZSet) RangeByScore(ctx context.Context, vc valkey.Conn, min, max float64) ([]string, []float64, error) {
+	return StringsWithScores(valkey.DoContext(vc, ctx, "ZRANGE", z.key, min, max, "BYSCORE", "WITHSCORES"))
+}
+
+func (z *CappedZSet) rank(ctx context.Context, vc valkey.Conn, cmd, member string) (int, error) {
+	rank, err := valkey.Int(valkey.DoContext(vc, ctx, cmd, z.key, member))
+	if err == valkey.ErrNil {
+		return -1, nil
+	}
+	return rank, err
+}
@@ -63,3 +63,74 @@ func TestCappedZSet(t *testing.T) {
 
 	assertMembers(zset, []string{"G", "E", "D"}, []float64{3.5, 4, 4.5})
 }
+
+func TestCappedZSetQueries(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	assertScore := func(z *vkutil.CappedZSet, member string, expectedScore float64, expectedExists bool) {
+		score, exists, err := z.Score(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedScore, score, "score mismatch for %s", member)
+		assert.Equal(t, expectedExists, exists, "exists mismatch for %s", member)
+	}
+	assertRanks := func(z *vkutil.CappedZSet, member string, expectedRank, expectedRevRank int) {
+		rank, err := z.Rank(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRank, rank, "rank mismatch for %s", member)
+
+		rank, err = z.RevRank(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedRevRank, rank, "rev rank mismatch for %s", member)
+	}
+	assertTop := func(z *vkutil.CappedZSet, n int, expectedMembers []string, expectedScores []float64) {
+		members, scores, err := z.Top(ctx, vc, n)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedMembers, members, "top %d members mismatch", n)
+		assert.Equal(t, expectedScores, scores, "top %d scores mismatch", n)
+	}
+	assertRangeByScore := func(z *vkutil.CappedZSet, min, max float64, expectedMembers []string, expectedScores []float64) {
+		members, scores, err := z.RangeByScore(ctx, vc, min, max)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedMembers, members, "range %v-%v members mismatch", min, max)
+		assert.Equal(t, expectedScores, scores, "range %v-%v scores mismatch", min, max)
+	}
+
+	zset := vkutil.NewCappedZSet("foo", 5, time.Minute*5)
+	assertScore(zset, "A", 0, false)
+	assertRanks(zset, "A", -1, -1)
+	assertTop(zset, 3, []string{}, []float64{})
+
+	zset.Add(ctx, vc, "A", 1)
+	zset.Add(ctx, vc, "B", 2.5)
+	zset.Add(ctx, vc, "C", 3)
+	zset.Add(ctx, vc, "D", 4)
+
+	assertScore(zset, "A", 1, true)
+	assertScore(zset, "B", 2.5, true)
+	assertScore(zset, "E", 0, false)
+
+	assertRanks(zset, "A", 0, 3)
+	assertRanks(zset, "C", 2, 1)
+	assertRanks(zset, "E", -1, -1)
+
+	assertTop(zset, 2, []string{"D", "C"}, []float64{4, 3})
+	assertTop(zset, 10, []string{"D", "C", "B", "A"}, []float64{4, 3, 2.5, 1})
+	assertTop(zset, 0, []string{}, []float64{})
+
+	assertRangeByScore(zset, 2, 3, []string{"B", "C"}, []float64{2.5, 3})
+	assertRangeByScore(zset, 5, 10, []string{}, []float64{})
+
+	assert.NoError(t, zset.Remove(ctx, vc, "B", "D", "E"))
+	assert.NoError(t, zset.Remove(ctx, vc)) // noop
+
+	assertvk.ZGetAll(t, vc, "foo", map[string]float64{"A": 1, "C": 3})
+
+	assertScore(zset, "B", 0, false)
+	assertRanks(zset, "C", 1, 0)
+	assertTop(zset, 3, []string{"C", "A"}, []float64{3, 1})
+}
@@ -0,0 +1,108 @@
+package vkutil
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// IntervalBitmap operates like a bitmap of numeric ids but with expiring intervals
+type IntervalBitmap struct {
+	intervals
+}
+
+// NewIntervalBitmap creates a new empty interval bitmap
+func NewIntervalBitmap(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalBitmap {
+	return &IntervalBitmap{intervals: newIntervals(keyBase, interval, size, opts)}
+}
+
+// Set sets the bit for the given id in the current interval
+func (b *IntervalBitmap) Set(ctx context.Context, vc valkey.Conn, id int64) error {
+	key := b.keys()[0]
+
+	vc.Send("MULTI")
+	vc.Send("SETBIT", key, id, 1)
+	vc.Send("EXPIRE", key, b.size*int(b.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+//go:embed lua/ibitmap_isset.lua
+var ibitmapIsSet string
+var ibitmapIsSetScript = valkey.NewScript(-1, ibitmapIsSet)
+
+// IsSet returns whether the bit for the given id is set in any interval
+func (b *IntervalBitmap) IsSet(ctx context.Context, vc valkey.Conn, id int64) (bool, error) {
+	keys := b.keys()
+
+	return valkey.Bool(ibitmapIsSetScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(id)...))
+}
+
+// Count returns the number of ids set in any interval
+func (b *IntervalBitmap) Count(ctx context.Context, vc valkey.Conn) (int64, error) {
+	return b.bitop(ctx, vc, "OR", b.tempKey(), 0)
+}
+
+// CountIntersection returns the number of ids set in every interval
+func (b *IntervalBitmap) CountIntersection(ctx context.Context, vc valkey.Conn) (int64, error) {
+	return b.bitop(ctx, vc, "AND", b.tempKey(), 0)
+}
+
+// CountPerInterval returns the number of ids set in each interval
+func (b *IntervalBitmap) CountPerInterval(ctx context.Context, vc valkey.Conn) ([]int64, error) {
+	vc.Send("MULTI")
+	for _, k := range b.keys() {
+		vc.Send("BITCOUNT", k)
+	}
+	return valkey.Int64s(valkey.DoContext(vc, ctx, "EXEC"))
+}
+
+// Union stores the union of all intervals in the given key with the given expiry, and returns the number of ids set
+// in it. Expiry must be at least a millisecond. To support cluster mode, dest should use the same hashtag as this
+// bitmap, i.e. {keyBase}.
+func (b *IntervalBitmap) Union(ctx context.Context, vc valkey.Conn, dest string, expire time.Duration) (int64, error) {
+	if expire < time.Millisecond {
+		return 0, fmt.Errorf("expire must be at least 1ms, got %s", expire)
+	}
+	return b.bitop(ctx, vc, "OR", dest, expire)
+}
+
+// Intersection stores the intersection of all intervals in the given key with the given expiry, and returns the
+// number of ids set in it. Expiry must be at least a millisecond. To support cluster mode, dest should use the same
+// hashtag as this bitmap, i.e. {keyBase}.
+func (b *IntervalBitmap) Intersection(ctx context.Context, vc valkey.Conn, dest string, expire time.Duration) (int64, error) {
+	if expire < time.Millisecond {
+		return 0, fmt.Errorf("expire must be at least 1ms, got %s", expire)
+	}
+	return b.bitop(ctx, vc, "AND", dest, expire)
+}
+
+// Clear removes all ids
+func (b *IntervalBitmap) Clear(ctx context.Context, vc valkey.Conn) error {
+	vc.Send("MULTI")
+	for _, k := range b.keys() {
+		vc.Send("DEL", k)
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+//go:embed lua/ibitmap_bitop.lua
+var ibitmapBitop string
+var ibitmapBitopScript = valkey.NewScript(-1, ibitmapBitop)
+
+// bitop performs the given BITOP operation across all intervals, storing the result in dest. If expire is zero, dest
+// is only used for counting and is deleted afterwards.
+func (b *IntervalBitmap) bitop(ctx context.Context, vc valkey.Conn, op, dest string, expire time.Duration) (int64, error) {
+	keys := b.keys()
+	args := valkey.Args{}.Add(len(keys)+1).Add(dest).AddFlat(keys).Add(op, expire.Milliseconds())
+
+	return valkey.Int64(ibitmapBitopScript.DoContext(ctx, vc, args...))
+}
+
+func (b *IntervalBitmap) tempKey() string {
+	return fmt.Sprintf("{%s}:tmp", b.keyBase)
+}
@@ -0,0 +1,107 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalBitmap(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertIsSet := func(b *vkutil.IntervalBitmap, id int64, expected bool) {
+		actual, err := b.IsSet(ctx, vc, id)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "is set mismatch for %d", id)
+	}
+	assertCounts := func(b *vkutil.IntervalBitmap, expectedUnion, expectedIntersection int64, expectedPerInterval []int64) {
+		actual, err := b.Count(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedUnion, actual, "count mismatch")
+
+		actual, err = b.CountIntersection(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedIntersection, actual, "count intersection mismatch")
+
+		actualPerInterval, err := b.CountPerInterval(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedPerInterval, actualPerInterval, "count per interval mismatch")
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// create a 1 hour x 2 based bitmap
+	bitmap1 := vkutil.NewIntervalBitmap("foos", time.Hour, 2)
+	assertIsSet(bitmap1, 1, false)
+	assertCounts(bitmap1, 0, 0, []int64{0, 0})
+
+	assert.NoError(t, bitmap1.Set(ctx, vc, 1))
+	assert.NoError(t, bitmap1.Set(ctx, vc, 5))
+	assert.NoError(t, bitmap1.Set(ctx, vc, 1000))
+
+	assertvk.Exists(t, vc, "{foos}:2021-11-18T12:00")
+
+	assertIsSet(bitmap1, 1, true)
+	assertIsSet(bitmap1, 2, false)
+	assertIsSet(bitmap1, 1000, true)
+	assertCounts(bitmap1, 3, 0, []int64{3, 0})
+
+	setNow(time.Date(2021, 11, 18, 13, 7, 3, 234567, time.UTC))
+
+	assert.NoError(t, bitmap1.Set(ctx, vc, 5))
+	assert.NoError(t, bitmap1.Set(ctx, vc, 7))
+
+	assertIsSet(bitmap1, 1, true)
+	assertIsSet(bitmap1, 7, true)
+	assertCounts(bitmap1, 4, 1, []int64{2, 3})
+
+	// temporary key used for counting is removed
+	assertvk.NotExists(t, vc, "{foos}:tmp")
+
+	count, err := bitmap1.Union(ctx, vc, "{foos}:union", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+
+	count, err = bitmap1.Intersection(ctx, vc, "{foos}:inter", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	assertvk.Exists(t, vc, "{foos}:union")
+	assertvk.Exists(t, vc, "{foos}:inter")
+
+	count, err = bitmap1.Union(ctx, vc, "{foos}:union2", time.Millisecond*500)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	assertvk.Exists(t, vc, "{foos}:union2")
+
+	// a non-positive expiry would mean the result isn't stored
+	_, err = bitmap1.Union(ctx, vc, "{foos}:union3", 0)
+	assert.EqualError(t, err, "expire must be at least 1ms, got 0s")
+	_, err = bitmap1.Intersection(ctx, vc, "{foos}:inter3", -time.Second)
+	assert.EqualError(t, err, "expire must be at least 1ms, got -1s")
+	assertvk.NotExists(t, vc, "{foos}:union3")
+	assertvk.NotExists(t, vc, "{foos}:inter3")
+
+	setNow(time.Date(2021, 11, 18, 14, 7, 3, 234567, time.UTC))
+
+	assertIsSet(bitmap1, 1, false)
+	assertIsSet(bitmap1, 5, true)
+	assertCounts(bitmap1, 2, 0, []int64{0, 2})
+
+	assert.NoError(t, bitmap1.Clear(ctx, vc))
+
+	assertIsSet(bitmap1, 5, false)
+	assertCounts(bitmap1, 0, 0, []int64{0, 0})
+}
@@ -0,0 +1,134 @@
+package vkutil
+
+import (
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// maximum number of bits in a bitmap value
+const maxBloomBits = 1 << 32
+
+// IntervalBloom operates like a bloom filter but with expiring intervals. It's implemented with plain bitmaps so
+// doesn't require any server modules.
+type IntervalBloom struct {
+	intervals
+
+	numBits   uint64 // number of bits in each interval's bitmap
+	numHashes int    // number of bits set per member
+}
+
+// NewIntervalBloom creates a new empty interval bloom filter, sized to hold the given number of expected items per
+// interval with the given false positive rate. Panics if expected items is less than 1 or the false positive rate isn't
+// between 0 and 1 (exclusive). Each interval's bitmap is capped at 2^32 bits (512MB).
+func NewIntervalBloom(keyBase string, interval time.Duration, size int, expectedItems int, fpRate float64, opts ...IntervalOption) *IntervalBloom {
+	if expectedItems < 1 {
+		panic(fmt.Sprintf("expected items must be at least 1, got %d", expectedItems))
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic(fmt.Sprintf("false positive rate must be between 0 and 1, got %g", fpRate))
+	}
+
+	numBits, numHashes := bloomParams(expectedItems, fpRate)
+
+	return &IntervalBloom{intervals: newIntervals(keyBase, interval, size, opts), numBits: numBits, numHashes: numHashes}
+}
+
+//go:embed lua/ibloom_add.lua
+var ibloomAdd string
+var ibloomAddScript = valkey.NewScript(1, ibloomAdd)
+
+// Add adds the given value to the current interval
+func (b *IntervalBloom) Add(ctx context.Context, vc valkey.Conn, member string) error {
+	return b.AddMany(ctx, vc, member)
+}
+
+// AddMany adds the given values to the current interval
+func (b *IntervalBloom) AddMany(ctx context.Context, vc valkey.Conn, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	args := valkey.Args{}.Add(b.keys()[0], b.size*int(b.interval/time.Second)).AddFlat(b.positions(members))
+
+	_, err := ibloomAddScript.DoContext(ctx, vc, args...)
+	return err
+}
+
+//go:embed lua/ibloom_check.lua
+var ibloomCheck string
+var ibloomCheckScript = valkey.NewScript(-1, ibloomCheck)
+
+// MightContain returns whether we might contain the given value. False positives are possible but false negatives
+// are not.
+func (b *IntervalBloom) MightContain(ctx context.Context, vc valkey.Conn, member string) (bool, error) {
+	found, err := b.MightContainMany(ctx, vc, member)
+	if err != nil {
+		return false, err
+	}
+	return found[0], nil
+}
+
+// MightContainMany returns whether we might contain each of the given values
+func (b *IntervalBloom) MightContainMany(ctx context.Context, vc valkey.Conn, members ...string) ([]bool, error) {
+	if len(members) == 0 {
+		return []bool{}, nil
+	}
+
+	keys := b.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(b.numHashes).AddFlat(b.positions(members))
+
+	found, err := valkey.Ints(ibloomCheckScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(found))
+	for i, f := range found {
+		results[i] = f == 1
+	}
+	return results, nil
+}
+
+// Clear removes all values
+func (b *IntervalBloom) Clear(ctx context.Context, vc valkey.Conn) error {
+	vc.Send("MULTI")
+	for _, k := range b.keys() {
+		vc.Send("DEL", k)
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// positions returns the bit positions for each of the given members, using double hashing of a 128-bit FNV-1a hash
+func (b *IntervalBloom) positions(members []string) []uint64 {
+	positions := make([]uint64, 0, len(members)*b.numHashes)
+
+	for _, m := range members {
+		h := fnv.New128a()
+		h.Write([]byte(m))
+		sum := h.Sum(nil)
+		h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])
+
+		for i := range b.numHashes {
+			positions = append(positions, (h1+uint64(i)*h2)%b.numBits)
+		}
+	}
+	return positions
+}
+
+// bloomParams calculates the optimal number of bits and hashes for the given number of items and false positive rate
+func bloomParams(expectedItems int, fpRate float64) (uint64, int) {
+	n, p := float64(expectedItems), fpRate
+
+	m := math.Ceil(-n * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / n * math.Ln2)
+
+	return uint64(math.Min(m, maxBloomBits)), max(int(k), 1)
+}
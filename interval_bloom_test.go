@@ -0,0 +1,110 @@
+package vkutil_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalBloom(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertMightContain := func(b *vkutil.IntervalBloom, member string, expected bool) {
+		actual, err := b.MightContain(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "might contain mismatch for %s", member)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 0, 3, 234567, time.UTC))
+
+	// create a 24-hour x 2 based bloom filter
+	bloom1 := vkutil.NewIntervalBloom("foos", time.Hour*24, 2, 1000, 0.01)
+	assertMightContain(bloom1, "A", false)
+
+	assert.NoError(t, bloom1.Add(ctx, vc, "A"))
+	assert.NoError(t, bloom1.AddMany(ctx, vc, "B", "C"))
+	assert.NoError(t, bloom1.AddMany(ctx, vc)) // noop
+
+	assertvk.Exists(t, vc, "{foos}:2021-11-18")
+	assertvk.NotExists(t, vc, "{foos}:2021-11-17")
+
+	assertMightContain(bloom1, "A", true)
+	assertMightContain(bloom1, "B", true)
+	assertMightContain(bloom1, "C", true)
+	assertMightContain(bloom1, "D", false)
+
+	setNow(time.Date(2021, 11, 19, 12, 0, 3, 234567, time.UTC))
+
+	assert.NoError(t, bloom1.Add(ctx, vc, "D"))
+
+	found, err := bloom1.MightContainMany(ctx, vc, "A", "B", "C", "D", "E")
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, true, false}, found)
+
+	found, err = bloom1.MightContainMany(ctx, vc)
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{}, found)
+
+	setNow(time.Date(2021, 11, 20, 12, 0, 3, 234567, time.UTC))
+
+	found, err = bloom1.MightContainMany(ctx, vc, "A", "B", "C", "D", "E")
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, false, true, false}, found)
+
+	assert.NoError(t, bloom1.Clear(ctx, vc))
+
+	assertMightContain(bloom1, "D", false)
+
+	// check false positive rate is roughly what was configured
+	bloom2 := vkutil.NewIntervalBloom("bars", time.Hour, 2, 1000, 0.01)
+
+	added := make([]string, 1000)
+	notAdded := make([]string, 1000)
+	for i := range 1000 {
+		added[i] = fmt.Sprintf("A%d", i)
+		notAdded[i] = fmt.Sprintf("N%d", i)
+	}
+	require.NoError(t, bloom2.AddMany(ctx, vc, added...))
+
+	found, err = bloom2.MightContainMany(ctx, vc, added...)
+	require.NoError(t, err)
+	assert.NotContains(t, found, false)
+
+	found, err = bloom2.MightContainMany(ctx, vc, notAdded...)
+	require.NoError(t, err)
+
+	falsePositives := 0
+	for _, f := range found {
+		if f {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 30)
+}
+
+func TestIntervalBloomInvalid(t *testing.T) {
+	assert.PanicsWithValue(t, "expected items must be at least 1, got 0", func() {
+		vkutil.NewIntervalBloom("foos", time.Hour, 2, 0, 0.01)
+	})
+	assert.PanicsWithValue(t, "false positive rate must be between 0 and 1, got 0", func() {
+		vkutil.NewIntervalBloom("foos", time.Hour, 2, 1000, 0)
+	})
+	assert.PanicsWithValue(t, "false positive rate must be between 0 and 1, got 1", func() {
+		vkutil.NewIntervalBloom("foos", time.Hour, 2, 1000, 1)
+	})
+	assert.NotPanics(t, func() { vkutil.NewIntervalBloom("foos", time.Hour, 2, 1, 0.99) })
+}
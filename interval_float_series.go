@@ -0,0 +1,67 @@
+package vkutil
+
+import (
+	"context"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// IntervalFloatSeries is like IntervalSeries but for float values, e.g. durations or costs
+type IntervalFloatSeries struct {
+	intervals
+}
+
+// NewIntervalFloatSeries creates a new empty float series
+func NewIntervalFloatSeries(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalFloatSeries {
+	return &IntervalFloatSeries{intervals: newIntervals(keyBase, interval, size, opts)}
+}
+
+// Record increments the value of field by value in the current interval
+func (s *IntervalFloatSeries) Record(ctx context.Context, vc valkey.Conn, field string, value float64) error {
+	currKey := s.keys()[0]
+
+	vc.Send("MULTI")
+	vc.Send("HINCRBYFLOAT", currKey, field, value)
+	vc.Send("EXPIRE", currKey, s.size*int(s.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// RecordMany increments the values of multiple fields in the current interval
+func (s *IntervalFloatSeries) RecordMany(ctx context.Context, vc valkey.Conn, values map[string]float64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	currKey := s.keys()[0]
+
+	vc.Send("MULTI")
+	for field, value := range values {
+		vc.Send("HINCRBYFLOAT", currKey, field, value)
+	}
+	vc.Send("EXPIRE", currKey, s.size*int(s.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// Get gets the values of field in all intervals
+func (s *IntervalFloatSeries) Get(ctx context.Context, vc valkey.Conn, field string) ([]float64, error) {
+	keys := s.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(field)
+
+	return valkey.Float64s(iseriesGetScript.DoContext(ctx, vc, args...))
+}
+
+// Total gets the total value of field across all intervals
+func (s *IntervalFloatSeries) Total(ctx context.Context, vc valkey.Conn, field string) (float64, error) {
+	vals, err := s.Get(ctx, vc, field)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, v := range vals {
+		total += v
+	}
+	return total, nil
+}
@@ -0,0 +1,60 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalFloatSeries(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertGet := func(s *vkutil.IntervalFloatSeries, f string, expected []float64) {
+		actual, err := s.Get(ctx, vc, f)
+		assert.NoError(t, err, "unexpected error getting field %s", f)
+		assert.InDeltaSlice(t, expected, actual, 0.0001, "expected series field %s to contain %v", f, expected)
+	}
+	assertTotal := func(s *vkutil.IntervalFloatSeries, f string, expected float64) {
+		actual, err := s.Total(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.InDelta(t, expected, actual, 0.0001)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// create a 5 minute x 3 based series
+	series1 := vkutil.NewIntervalFloatSeries("foos", time.Minute*5, 3)
+	assert.NoError(t, series1.Record(ctx, vc, "A", 1.5))
+	assert.NoError(t, series1.Record(ctx, vc, "A", 0.25))
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]float64{"A": 1, "B": 0.1}))
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]float64{})) // noop
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:05", map[string]string{"A": "2.75", "B": "0.1"})
+
+	assertGet(series1, "A", []float64{2.75, 0, 0})
+	assertGet(series1, "B", []float64{0.1, 0, 0})
+	assertGet(series1, "C", []float64{0, 0, 0})
+
+	setNow(time.Date(2021, 11, 18, 12, 11, 3, 234567, time.UTC)) // move time forward to next interval
+
+	series1.Record(ctx, vc, "A", -0.5)
+	series1.Record(ctx, vc, "B", 0.2)
+
+	assertGet(series1, "A", []float64{-0.5, 2.75, 0})
+	assertGet(series1, "B", []float64{0.2, 0.1, 0})
+	assertTotal(series1, "A", 2.25)
+	assertTotal(series1, "B", 0.3)
+	assertTotal(series1, "C", 0)
+}
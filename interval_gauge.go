@@ -0,0 +1,93 @@
+package vkutil
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// GaugeStats are the statistics of observations of a gauge field
+type GaugeStats struct {
+	Min   float64
+	Max   float64
+	Last  float64
+	Count int64
+}
+
+// IntervalGauge records the min, max and last observed values of fields in interval based hashes
+type IntervalGauge struct {
+	intervals
+}
+
+// NewIntervalGauge creates a new empty gauge
+func NewIntervalGauge(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalGauge {
+	return &IntervalGauge{intervals: newIntervals(keyBase, interval, size, opts)}
+}
+
+//go:embed lua/igauge_observe.lua
+var igaugeObserve string
+var igaugeObserveScript = valkey.NewScript(1, igaugeObserve)
+
+// Observe records an observation of field in the current interval
+func (g *IntervalGauge) Observe(ctx context.Context, vc valkey.Conn, field string, value float64) error {
+	_, err := igaugeObserveScript.DoContext(ctx, vc, g.keys()[0], field, value, g.size*int(g.interval/time.Second))
+	return err
+}
+
+// Get gets the stats of field in all intervals. Intervals without observations will have a zero count.
+func (g *IntervalGauge) Get(ctx context.Context, vc valkey.Conn, field string) ([]GaugeStats, error) {
+	keys := g.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(field+":min", field+":max", field+":last", field+":count")
+
+	perKey, err := valkey.Values(iseriesMGetScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]GaugeStats, len(perKey))
+	for i, reply := range perKey {
+		vals, err := valkey.Float64s(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+		stats[i] = GaugeStats{Min: vals[0], Max: vals[1], Last: vals[2], Count: int64(vals[3])}
+	}
+
+	return stats, nil
+}
+
+// Aggregate gets the stats of field across all intervals, where last is the most recently observed value
+func (g *IntervalGauge) Aggregate(ctx context.Context, vc valkey.Conn, field string) (GaugeStats, error) {
+	stats, err := g.Get(ctx, vc, field)
+	if err != nil {
+		return GaugeStats{}, err
+	}
+
+	var agg GaugeStats
+	for _, s := range stats {
+		if s.Count == 0 {
+			continue
+		}
+		if agg.Count == 0 {
+			agg = s
+			continue
+		}
+
+		agg.Min = min(agg.Min, s.Min)
+		agg.Max = max(agg.Max, s.Max)
+		agg.Count += s.Count
+	}
+	return agg, nil
+}
+
+// Clear removes all observations
+func (g *IntervalGauge) Clear(ctx context.Context, vc valkey.Conn) error {
+	vc.Send("MULTI")
+	for _, k := range g.keys() {
+		vc.Send("DEL", k)
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
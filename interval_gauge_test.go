@@ -0,0 +1,74 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalGauge(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertGet := func(g *vkutil.IntervalGauge, f string, expected []vkutil.GaugeStats) {
+		actual, err := g.Get(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "get mismatch for field %s", f)
+	}
+	assertAggregate := func(g *vkutil.IntervalGauge, f string, expected vkutil.GaugeStats) {
+		actual, err := g.Aggregate(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "aggregate mismatch for field %s", f)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// create a 5 minute x 3 based gauge
+	gauge1 := vkutil.NewIntervalGauge("foos", time.Minute*5, 3)
+	assertGet(gauge1, "A", []vkutil.GaugeStats{{}, {}, {}})
+	assertAggregate(gauge1, "A", vkutil.GaugeStats{})
+
+	assert.NoError(t, gauge1.Observe(ctx, vc, "A", 10))
+	assert.NoError(t, gauge1.Observe(ctx, vc, "A", 25.5))
+	assert.NoError(t, gauge1.Observe(ctx, vc, "A", 5))
+	assert.NoError(t, gauge1.Observe(ctx, vc, "A", 12))
+	assert.NoError(t, gauge1.Observe(ctx, vc, "B", -3))
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:05", map[string]string{
+		"A:min": "5", "A:max": "25.5", "A:last": "12", "A:count": "4",
+		"B:min": "-3", "B:max": "-3", "B:last": "-3", "B:count": "1",
+	})
+
+	assertGet(gauge1, "A", []vkutil.GaugeStats{{Min: 5, Max: 25.5, Last: 12, Count: 4}, {}, {}})
+	assertGet(gauge1, "B", []vkutil.GaugeStats{{Min: -3, Max: -3, Last: -3, Count: 1}, {}, {}})
+
+	setNow(time.Date(2021, 11, 18, 12, 16, 3, 234567, time.UTC)) // move forward 2 intervals
+
+	gauge1.Observe(ctx, vc, "A", 7)
+	gauge1.Observe(ctx, vc, "A", 30)
+	gauge1.Observe(ctx, vc, "A", 8)
+
+	assertGet(gauge1, "A", []vkutil.GaugeStats{{Min: 7, Max: 30, Last: 8, Count: 3}, {}, {Min: 5, Max: 25.5, Last: 12, Count: 4}})
+	assertAggregate(gauge1, "A", vkutil.GaugeStats{Min: 5, Max: 30, Last: 8, Count: 7})
+	assertAggregate(gauge1, "B", vkutil.GaugeStats{Min: -3, Max: -3, Last: -3, Count: 1})
+
+	setNow(time.Date(2021, 11, 18, 12, 21, 3, 234567, time.UTC))
+
+	assertAggregate(gauge1, "A", vkutil.GaugeStats{Min: 7, Max: 30, Last: 8, Count: 3})
+	assertAggregate(gauge1, "B", vkutil.GaugeStats{})
+
+	assert.NoError(t, gauge1.Clear(ctx, vc))
+
+	assertAggregate(gauge1, "A", vkutil.GaugeStats{})
+}
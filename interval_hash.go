@@ -4,21 +4,26 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"iter"
 	"time"
 
 	valkey "github.com/gomodule/redigo/redis"
 )
 
+// HashEntry is a field and value pair in a hash
+type HashEntry struct {
+	Field string
+	Value string
+}
+
 // IntervalHash operates like a hash map but with expiring intervals
 type IntervalHash struct {
-	keyBase  string
-	interval time.Duration // e.g. 5 minutes
-	size     int           // number of intervals
+	intervals
 }
 
 // NewIntervalHash creates a new empty interval hash
-func NewIntervalHash(keyBase string, interval time.Duration, size int) *IntervalHash {
-	return &IntervalHash{keyBase: keyBase, interval: interval, size: size}
+func NewIntervalHash(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalHash {
+	return &IntervalHash{intervals: newIntervals(keyBase, interval, size, opts)}
 }
 
 //go:embed lua/ihash_get.lua
@@ -87,6 +92,48 @@ func (h *IntervalHash) Clear(ctx context.Context, vc valkey.Conn) error {
 	return err
 }
 
-func (h *IntervalHash) keys() []string {
-	return intervalKeys(h.keyBase, h.interval, h.size)
+//go:embed lua/ihash_exists.lua
+var ihashExists string
+var ihashExistsScript = valkey.NewScript(-1, ihashExists)
+
+// Scan returns an iterator over all fields across all intervals, using HSCAN so that large hashes don't need to be
+// loaded into memory at once. Fields are iterated from newest to oldest interval and if a field exists in multiple
+// intervals only the newest value is returned, which is checked against the newer intervals on the server rather than
+// by tracking returned fields. As with HSCAN itself, a field which is set or deleted during iteration may or may not be
+// returned, and a field may be returned more than once if its interval is rehashed during iteration. If an error
+// occurs it is yielded with an empty entry and iteration stops.
+func (h *IntervalHash) Scan(ctx context.Context, vc valkey.Conn, opts ...ScanOption) iter.Seq2[HashEntry, error] {
+	return func(yield func(HashEntry, error) bool) {
+		keys := h.keys()
+
+		err := scanKeys(ctx, vc, "HSCAN", keys, newScanOptions(opts), func(i int, fvs []string) (bool, error) {
+			var inNewer []int
+
+			// fields which exist in a newer interval have already been returned
+			if i > 0 {
+				fields := make([]string, 0, len(fvs)/2)
+				for j := 0; j+1 < len(fvs); j += 2 {
+					fields = append(fields, fvs[j])
+				}
+
+				var err error
+				inNewer, err = valkey.Ints(ihashExistsScript.DoContext(ctx, vc, valkey.Args{}.Add(i).AddFlat(keys[:i]).AddFlat(fields)...))
+				if err != nil {
+					return false, err
+				}
+			}
+
+			for j := 0; j+1 < len(fvs); j += 2 {
+				if inNewer == nil || inNewer[j/2] == 0 {
+					if !yield(HashEntry{Field: fvs[j], Value: fvs[j+1]}, nil) {
+						return false, nil
+					}
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			yield(HashEntry{}, err)
+		}
+	}
 }
@@ -142,3 +142,48 @@ func TestIntervalHash(t *testing.T) {
 	assertGet(hash3, "B", "2")
 	assertGet(hash3, "C", "")
 }
+
+func TestIntervalHashScan(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	scanAll := func(h *vkutil.IntervalHash, opts ...vkutil.ScanOption) map[string]string {
+		fields := map[string]string{}
+		for e, err := range h.Scan(ctx, vc, opts...) {
+			require.NoError(t, err)
+			_, seen := fields[e.Field]
+			assert.False(t, seen, "field %s returned more than once", e.Field)
+			fields[e.Field] = e.Value
+		}
+		return fields
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	hash1 := vkutil.NewIntervalHash("foos", time.Hour*24, 2)
+	assert.Equal(t, map[string]string{}, scanAll(hash1))
+
+	hash1.Set(ctx, vc, "A1", "1")
+	hash1.Set(ctx, vc, "A2", "2")
+	hash1.Set(ctx, vc, "B1", "3")
+
+	setNow(time.Date(2021, 11, 19, 12, 7, 3, 234567, time.UTC))
+
+	hash1.Set(ctx, vc, "A1", "4")
+	hash1.Set(ctx, vc, "B2", "5")
+
+	assert.Equal(t, map[string]string{"A1": "4", "A2": "2", "B1": "3", "B2": "5"}, scanAll(hash1))
+	assert.Equal(t, map[string]string{"A1": "4", "A2": "2", "B1": "3", "B2": "5"}, scanAll(hash1, vkutil.WithCount(1)))
+	assert.Equal(t, map[string]string{"B1": "3", "B2": "5"}, scanAll(hash1, vkutil.WithMatch("B*")))
+
+	setNow(time.Date(2021, 11, 20, 12, 7, 3, 234567, time.UTC))
+
+	assert.Equal(t, map[string]string{"A1": "4", "B2": "5"}, scanAll(hash1))
+}
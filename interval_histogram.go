@@ -0,0 +1,173 @@
+package vkutil
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// IntervalHistogram records the distribution of observed values of fields into buckets in interval based hashes
+type IntervalHistogram struct {
+	intervals
+
+	bounds []float64 // upper bounds of buckets, with an implicit final +Inf bucket
+}
+
+// NewIntervalHistogram creates a new empty histogram with the given bucket upper bounds, panicking if they aren't
+// finite and unique. Note that changing the bounds of an existing histogram will make existing data invalid.
+func NewIntervalHistogram(keyBase string, interval time.Duration, size int, bounds []float64, opts ...IntervalOption) *IntervalHistogram {
+	bounds = slices.Clone(bounds)
+	slices.Sort(bounds)
+
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			panic(fmt.Sprintf("bucket bounds must be finite, got %g", b))
+		}
+		if i > 0 && b == bounds[i-1] {
+			panic(fmt.Sprintf("bucket bounds must be unique, got %g more than once", b))
+		}
+	}
+
+	return &IntervalHistogram{intervals: newIntervals(keyBase, interval, size, opts), bounds: bounds}
+}
+
+// Observe records an observation of field in the current interval
+func (h *IntervalHistogram) Observe(ctx context.Context, vc valkey.Conn, field string, value float64) error {
+	currKey := h.keys()[0]
+	bucket := sort.SearchFloat64s(h.bounds, value)
+
+	vc.Send("MULTI")
+	vc.Send("HINCRBY", currKey, h.bucketField(field, bucket), 1)
+	vc.Send("HINCRBYFLOAT", currKey, field+":sum", value)
+	vc.Send("EXPIRE", currKey, h.size*int(h.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// Get gets the distribution of observations of field across all intervals
+func (h *IntervalHistogram) Get(ctx context.Context, vc valkey.Conn, field string) (*HistogramSnapshot, error) {
+	keys := h.keys()
+	fields := make([]string, 0, len(h.bounds)+2)
+	for b := range len(h.bounds) + 1 {
+		fields = append(fields, h.bucketField(field, b))
+	}
+	fields = append(fields, field+":sum")
+
+	perKey, err := valkey.Values(iseriesMGetScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys).AddFlat(fields)...))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &HistogramSnapshot{Bounds: h.bounds, Buckets: make([]int64, len(h.bounds)+1)}
+
+	for _, reply := range perKey {
+		vals, err := valkey.Values(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for b := range snapshot.Buckets {
+			count, err := valkey.Int64(vals[b], nil)
+			if err != nil && err != valkey.ErrNil {
+				return nil, err
+			}
+			snapshot.Buckets[b] += count
+			snapshot.Count += count
+		}
+
+		sum, err := valkey.Float64(vals[len(vals)-1], nil)
+		if err != nil && err != valkey.ErrNil {
+			return nil, err
+		}
+		snapshot.Sum += sum
+	}
+
+	return snapshot, nil
+}
+
+// Quantiles gets estimates of the given quantiles (e.g. 0.5, 0.99) of field across all intervals
+func (h *IntervalHistogram) Quantiles(ctx context.Context, vc valkey.Conn, field string, qs ...float64) ([]float64, error) {
+	snapshot, err := h.Get(ctx, vc, field)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(qs))
+	for i, q := range qs {
+		values[i] = snapshot.Quantile(q)
+	}
+	return values, nil
+}
+
+// Clear removes all observations
+func (h *IntervalHistogram) Clear(ctx context.Context, vc valkey.Conn) error {
+	vc.Send("MULTI")
+	for _, k := range h.keys() {
+		vc.Send("DEL", k)
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+func (h *IntervalHistogram) bucketField(field string, bucket int) string {
+	if bucket == len(h.bounds) {
+		return field + ":inf"
+	}
+	return fmt.Sprintf("%s:%s", field, strconv.FormatFloat(h.bounds[bucket], 'g', -1, 64))
+}
+
+// HistogramSnapshot is the distribution of observations of a histogram field
+type HistogramSnapshot struct {
+	Bounds  []float64 // upper bounds of buckets
+	Buckets []int64   // counts of each bucket, with the final bucket for values above all bounds
+	Count   int64
+	Sum     float64
+}
+
+// Mean returns the mean of all observations
+func (s *HistogramSnapshot) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// Quantile estimates the given quantile by linear interpolation within the bucket it falls in. The first bucket is
+// assumed to start at zero, i.e. observations are assumed to be non-negative, unless its bound isn't positive, in which
+// case values in it are estimated as that bound. Values in the final bucket are estimated as the largest bound.
+func (s *HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 || len(s.Bounds) == 0 {
+		return 0
+	}
+
+	rank := max(min(q, 1), 0) * float64(s.Count)
+	var cumulative int64
+
+	for b, count := range s.Buckets {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if b == len(s.Bounds) {
+			break
+		}
+
+		upper := s.Bounds[b]
+		lower := 0.0
+		if b > 0 {
+			lower = s.Bounds[b-1]
+		} else if upper <= 0 {
+			return upper
+		}
+
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(count)
+	}
+
+	return s.Bounds[len(s.Bounds)-1]
+}
@@ -0,0 +1,94 @@
+package vkutil_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalHistogram(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertQuantiles := func(h *vkutil.IntervalHistogram, f string, qs []float64, expected []float64) {
+		actual, err := h.Quantiles(ctx, vc, f, qs...)
+		assert.NoError(t, err)
+		assert.InDeltaSlice(t, expected, actual, 0.0001, "quantiles mismatch for field %s", f)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// create a 5 minute x 3 based histogram, bounds don't have to be given in order
+	hist1 := vkutil.NewIntervalHistogram("foos", time.Minute*5, 3, []float64{50, 10, 500, 100})
+
+	snapshot, err := hist1.Get(ctx, vc, "A")
+	require.NoError(t, err)
+	assert.Equal(t, &vkutil.HistogramSnapshot{Bounds: []float64{10, 50, 100, 500}, Buckets: []int64{0, 0, 0, 0, 0}}, snapshot)
+	assert.Equal(t, 0.0, snapshot.Mean())
+	assertQuantiles(hist1, "A", []float64{0.5}, []float64{0})
+
+	for _, v := range []float64{5, 8, 20, 30} {
+		assert.NoError(t, hist1.Observe(ctx, vc, "A", v))
+	}
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:05", map[string]string{"A:10": "2", "A:50": "2", "A:sum": "63"})
+
+	setNow(time.Date(2021, 11, 18, 12, 11, 3, 234567, time.UTC))
+
+	for _, v := range []float64{40, 75, 200, 1000} {
+		assert.NoError(t, hist1.Observe(ctx, vc, "A", v))
+	}
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:10", map[string]string{"A:50": "1", "A:100": "1", "A:500": "1", "A:inf": "1", "A:sum": "1315"})
+
+	snapshot, err = hist1.Get(ctx, vc, "A")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3, 1, 1, 1}, snapshot.Buckets)
+	assert.Equal(t, int64(8), snapshot.Count)
+	assert.Equal(t, 1378.0, snapshot.Sum)
+	assert.Equal(t, 172.25, snapshot.Mean())
+
+	assertQuantiles(hist1, "A", []float64{0, 0.25, 0.5, 0.75, 0.99}, []float64{0, 10, 36.6667, 100, 500})
+	assertQuantiles(hist1, "B", []float64{0.5}, []float64{0})
+
+	setNow(time.Date(2021, 11, 18, 12, 21, 3, 234567, time.UTC)) // first interval now too old
+
+	snapshot, err = hist1.Get(ctx, vc, "A")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 1, 1, 1, 1}, snapshot.Buckets)
+	assert.Equal(t, int64(4), snapshot.Count)
+
+	assertQuantiles(hist1, "A", []float64{0.5}, []float64{100})
+
+	assert.NoError(t, hist1.Clear(ctx, vc))
+
+	snapshot, err = hist1.Get(ctx, vc, "A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), snapshot.Count)
+}
+
+func TestIntervalHistogramInvalid(t *testing.T) {
+	assert.PanicsWithValue(t, "bucket bounds must be unique, got 10 more than once", func() {
+		vkutil.NewIntervalHistogram("foos", time.Hour, 2, []float64{10, 50, 10})
+	})
+	assert.PanicsWithValue(t, "bucket bounds must be finite, got NaN", func() {
+		vkutil.NewIntervalHistogram("foos", time.Hour, 2, []float64{10, math.NaN()})
+	})
+	assert.PanicsWithValue(t, "bucket bounds must be finite, got +Inf", func() {
+		vkutil.NewIntervalHistogram("foos", time.Hour, 2, []float64{10, math.Inf(1)})
+	})
+	assert.NotPanics(t, func() { vkutil.NewIntervalHistogram("foos", time.Hour, 2, []float64{}) })
+}
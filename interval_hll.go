@@ -0,0 +1,57 @@
+package vkutil
+
+import (
+	"context"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// IntervalHLL operates like a HyperLogLog but with expiring intervals, for approximate counting of unique values
+type IntervalHLL struct {
+	intervals
+}
+
+// NewIntervalHLL creates a new empty interval HyperLogLog
+func NewIntervalHLL(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalHLL {
+	return &IntervalHLL{intervals: newIntervals(keyBase, interval, size, opts)}
+}
+
+// Add adds the given values to the current interval
+func (h *IntervalHLL) Add(ctx context.Context, vc valkey.Conn, values ...string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	key := h.keys()[0]
+
+	vc.Send("MULTI")
+	vc.Send("PFADD", valkey.Args{}.Add(key).AddFlat(values)...)
+	vc.Send("EXPIRE", key, h.size*int(h.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// Count returns the approximate number of unique values across all intervals
+func (h *IntervalHLL) Count(ctx context.Context, vc valkey.Conn) (int64, error) {
+	return valkey.Int64(valkey.DoContext(vc, ctx, "PFCOUNT", valkey.Args{}.AddFlat(h.keys())...))
+}
+
+// CountPerInterval returns the approximate number of unique values in each interval
+func (h *IntervalHLL) CountPerInterval(ctx context.Context, vc valkey.Conn) ([]int64, error) {
+	vc.Send("MULTI")
+	for _, k := range h.keys() {
+		vc.Send("PFCOUNT", k)
+	}
+	return valkey.Int64s(valkey.DoContext(vc, ctx, "EXEC"))
+}
+
+// Clear removes all values
+func (h *IntervalHLL) Clear(ctx context.Context, vc valkey.Conn) error {
+	vc.Send("MULTI")
+	for _, k := range h.keys() {
+		vc.Send("DEL", k)
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
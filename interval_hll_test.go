@@ -0,0 +1,63 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntervalHLL(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertCount := func(h *vkutil.IntervalHLL, expected int64, expectedPerInterval []int64) {
+		actual, err := h.Count(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "count mismatch")
+
+		actualPerInterval, err := h.CountPerInterval(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedPerInterval, actualPerInterval, "count per interval mismatch")
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// create a 1 hour x 3 based HLL
+	hll1 := vkutil.NewIntervalHLL("foos", time.Hour, 3)
+	assertCount(hll1, 0, []int64{0, 0, 0})
+
+	assert.NoError(t, hll1.Add(ctx, vc)) // noop
+	assertvk.NotExists(t, vc, "{foos}:2021-11-18T12:00")
+
+	assert.NoError(t, hll1.Add(ctx, vc, "A", "B", "C"))
+	assert.NoError(t, hll1.Add(ctx, vc, "A"))
+
+	assertvk.Exists(t, vc, "{foos}:2021-11-18T12:00")
+	assertCount(hll1, 3, []int64{3, 0, 0})
+
+	setNow(time.Date(2021, 11, 18, 13, 7, 3, 234567, time.UTC))
+
+	assert.NoError(t, hll1.Add(ctx, vc, "B", "D"))
+
+	assertCount(hll1, 4, []int64{2, 3, 0})
+
+	setNow(time.Date(2021, 11, 18, 15, 7, 3, 234567, time.UTC))
+
+	assertCount(hll1, 2, []int64{0, 0, 2})
+
+	assert.NoError(t, hll1.Clear(ctx, vc))
+
+	assertvk.NotExists(t, vc, "{foos}:2021-11-18T13:00")
+	assertCount(hll1, 0, []int64{0, 0, 0})
+}
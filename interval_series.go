@@ -8,16 +8,20 @@ import (
 	valkey "github.com/gomodule/redigo/redis"
 )
 
+// Point is the value of a series field in the interval starting at the given time
+type Point struct {
+	Start time.Time
+	Value int64
+}
+
 // IntervalSeries returns all values from interval based hashes.
 type IntervalSeries struct {
-	keyBase  string
-	interval time.Duration // e.g. 5 minutes
-	size     int           // number of intervals
+	intervals
 }
 
 // NewIntervalSeries creates a new empty series
-func NewIntervalSeries(keyBase string, interval time.Duration, size int) *IntervalSeries {
-	return &IntervalSeries{keyBase: keyBase, interval: interval, size: size}
+func NewIntervalSeries(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalSeries {
+	return &IntervalSeries{intervals: newIntervals(keyBase, interval, size, opts)}
 }
 
 // Record increments the value of field by value in the current interval
@@ -31,18 +35,77 @@ func (s *IntervalSeries) Record(ctx context.Context, vc valkey.Conn, field strin
 	return err
 }
 
+// RecordMany increments the values of multiple fields in the current interval
+func (s *IntervalSeries) RecordMany(ctx context.Context, vc valkey.Conn, values map[string]int64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	currKey := s.keys()[0]
+
+	vc.Send("MULTI")
+	for field, value := range values {
+		vc.Send("HINCRBY", currKey, field, value)
+	}
+	vc.Send("EXPIRE", currKey, s.size*int(s.interval/time.Second))
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
 //go:embed lua/iseries_get.lua
 var iseriesGet string
 var iseriesGetScript = valkey.NewScript(-1, iseriesGet)
 
 // Get gets the values of field in all intervals
 func (s *IntervalSeries) Get(ctx context.Context, vc valkey.Conn, field string) ([]int64, error) {
-	keys := s.keys()
+	return s.getAt(ctx, vc, field, s.clock.Now())
+}
+
+// getAt gets the values of field in all intervals as of the given time
+func (s *IntervalSeries) getAt(ctx context.Context, vc valkey.Conn, field string, now time.Time) ([]int64, error) {
+	keys := s.keysAt(now)
 	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(field)
 
 	return valkey.Int64s(iseriesGetScript.DoContext(ctx, vc, args...))
 }
 
+// GetPoints gets the values of field in all intervals along with the start time of each interval
+func (s *IntervalSeries) GetPoints(ctx context.Context, vc valkey.Conn, field string) ([]Point, error) {
+	return s.Range(ctx, vc, field, time.Time{}, s.clock.Now().Add(s.interval))
+}
+
+// Range gets the values of field in the intervals which overlap the given time range, along with the start time of
+// each interval. Like Get, points are ordered from newest to oldest.
+func (s *IntervalSeries) Range(ctx context.Context, vc valkey.Conn, field string, from, to time.Time) ([]Point, error) {
+	now := s.clock.Now()
+	curr := s.wallStart(now)
+
+	var keys []string
+	var points []Point
+	for i, k := range s.keysAt(now) {
+		wall := curr.Add(-s.interval * time.Duration(i))
+		start, end := s.fromWall(wall), s.fromWall(wall.Add(s.interval))
+		if start.Before(to) && end.After(from) {
+			keys = append(keys, k)
+			points = append(points, Point{Start: start})
+		}
+	}
+
+	if len(keys) == 0 {
+		return []Point{}, nil
+	}
+
+	vals, err := valkey.Int64s(iseriesGetScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(field)...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range vals {
+		points[i].Value = v
+	}
+	return points, nil
+}
+
 // Total gets the total value of field across all intervals
 func (s *IntervalSeries) Total(ctx context.Context, vc valkey.Conn, field string) (int64, error) {
 	vals, err := s.Get(ctx, vc, field)
@@ -56,6 +119,179 @@ func (s *IntervalSeries) Total(ctx context.Context, vc valkey.Conn, field string
 	return total, nil
 }
 
-func (s *IntervalSeries) keys() []string {
-	return intervalKeys(s.keyBase, s.interval, s.size)
+// WeightedTotal estimates the total value of field over a sliding window of size-1 intervals ending now, by only
+// counting the part of the oldest interval which falls inside that window. Unlike Total, this doesn't dip at the start
+// of each interval. For a series with a single interval, this is the same as Total.
+func (s *IntervalSeries) WeightedTotal(ctx context.Context, vc valkey.Conn, field string) (float64, error) {
+	return s.weightedTotalAt(ctx, vc, field, s.clock.Now())
+}
+
+// weightedTotalAt calculates the weighted total of field as of the given time
+func (s *IntervalSeries) weightedTotalAt(ctx context.Context, vc valkey.Conn, field string, now time.Time) (float64, error) {
+	vals, err := s.getAt(ctx, vc, field, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(vals) == 1 {
+		return float64(vals[0]), nil
+	}
+
+	progress := s.progress(now)
+	var total float64
+	for i, v := range vals {
+		if i == len(vals)-1 {
+			total += float64(v) * (1 - progress)
+		} else {
+			total += float64(v)
+		}
+	}
+	return total, nil
+}
+
+// Rate estimates the per second rate of field over the same sliding window as WeightedTotal
+func (s *IntervalSeries) Rate(ctx context.Context, vc valkey.Conn, field string) (float64, error) {
+	now := s.clock.Now()
+
+	total, err := s.weightedTotalAt(ctx, vc, field, now)
+	if err != nil {
+		return 0, err
+	}
+
+	window := s.interval * time.Duration(s.size-1)
+	if s.size == 1 {
+		window = time.Duration(float64(s.interval) * s.progress(now))
+	}
+	if window <= 0 {
+		return 0, nil
+	}
+
+	return total / window.Seconds(), nil
+}
+
+// Average estimates the average value of field per interval over the same sliding window as WeightedTotal
+func (s *IntervalSeries) Average(ctx context.Context, vc valkey.Conn, field string) (float64, error) {
+	rate, err := s.Rate(ctx, vc, field)
+	if err != nil {
+		return 0, err
+	}
+
+	return rate * s.interval.Seconds(), nil
+}
+
+//go:embed lua/iseries_mget.lua
+var iseriesMGet string
+var iseriesMGetScript = valkey.NewScript(-1, iseriesMGet)
+
+// GetMany gets the values of each of the given fields in all intervals
+func (s *IntervalSeries) GetMany(ctx context.Context, vc valkey.Conn, fields ...string) ([][]int64, error) {
+	if len(fields) == 0 {
+		return [][]int64{}, nil
+	}
+
+	keys := s.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).AddFlat(fields)
+
+	perKey, err := valkey.Values(iseriesMGetScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([][]int64, len(fields))
+	for f := range values {
+		values[f] = make([]int64, len(keys))
+	}
+
+	for k, reply := range perKey {
+		vals, err := valkey.Int64s(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+		for f, v := range vals {
+			values[f][k] = v
+		}
+	}
+
+	return values, nil
+}
+
+//go:embed lua/iseries_getall.lua
+var iseriesGetAll string
+var iseriesGetAllScript = valkey.NewScript(-1, iseriesGetAll)
+
+// GetAll gets the values of every field that has a value in any interval
+func (s *IntervalSeries) GetAll(ctx context.Context, vc valkey.Conn) (map[string][]int64, error) {
+	keys := s.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys)
+
+	perKey, err := valkey.Values(iseriesGetAllScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]int64)
+
+	for k, reply := range perKey {
+		pairs, err := valkey.Int64Map(reply, nil)
+		if err != nil {
+			return nil, err
+		}
+		for field, v := range pairs {
+			if values[field] == nil {
+				values[field] = make([]int64, len(keys))
+			}
+			values[field][k] = v
+		}
+	}
+
+	return values, nil
+}
+
+// Totals gets the total values of each of the given fields across all intervals
+func (s *IntervalSeries) Totals(ctx context.Context, vc valkey.Conn, fields ...string) ([]int64, error) {
+	vals, err := s.GetMany(ctx, vc, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make([]int64, len(vals))
+	for i, fv := range vals {
+		for _, v := range fv {
+			totals[i] += v
+		}
+	}
+	return totals, nil
+}
+
+//go:embed lua/iseries_topn.lua
+var iseriesTopN string
+var iseriesTopNScript = valkey.NewScript(-1, iseriesTopN)
+
+// TopN gets the n fields with the largest totals across all intervals, ordered by descending total
+func (s *IntervalSeries) TopN(ctx context.Context, vc valkey.Conn, n int) ([]string, []int64, error) {
+	if n <= 0 {
+		return []string{}, []int64{}, nil
+	}
+
+	keys := s.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(n)
+
+	pairs, err := valkey.Values(iseriesTopNScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fields := make([]string, len(pairs)/2)
+	totals := make([]int64, len(pairs)/2)
+
+	for i := range fields {
+		if fields[i], err = valkey.String(pairs[2*i], nil); err != nil {
+			return nil, nil, err
+		}
+		if totals[i], err = valkey.Int64(pairs[2*i+1], nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return fields, totals, nil
 }
@@ -102,3 +102,252 @@ func TestIntervalSeries(t *testing.T) {
 	assertTotal(series1, "B", 3)
 	assertTotal(series1, "C", 0)
 }
+
+func TestIntervalSeriesRecordMany(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	series1 := vkutil.NewIntervalSeries("foos", time.Minute*5, 3)
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]int64{"sent": 3, "delivered": 2, "failed": 1}))
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]int64{"sent": 1, "delivered": 1}))
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]int64{})) // noop
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:05", map[string]string{"sent": "4", "delivered": "3", "failed": "1"})
+
+	setNow(time.Date(2021, 11, 18, 12, 11, 3, 234567, time.UTC))
+
+	assert.NoError(t, series1.RecordMany(ctx, vc, map[string]int64{"sent": 2, "failed": 2}))
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:10", map[string]string{"sent": "2", "failed": "2"})
+
+	vals, err := series1.Get(ctx, vc, "sent")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 4, 0}, vals)
+}
+
+func TestIntervalSeriesMultipleFields(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertGetMany := func(s *vkutil.IntervalSeries, fs []string, expected [][]int64) {
+		actual, err := s.GetMany(ctx, vc, fs...)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "get many mismatch for %v", fs)
+	}
+	assertGetAll := func(s *vkutil.IntervalSeries, expected map[string][]int64) {
+		actual, err := s.GetAll(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "get all mismatch")
+	}
+	assertTotals := func(s *vkutil.IntervalSeries, fs []string, expected []int64) {
+		actual, err := s.Totals(ctx, vc, fs...)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "totals mismatch for %v", fs)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	series1 := vkutil.NewIntervalSeries("foos", time.Minute*5, 3)
+
+	assertGetMany(series1, []string{"A", "B"}, [][]int64{{0, 0, 0}, {0, 0, 0}})
+	assertGetMany(series1, []string{}, [][]int64{})
+	assertGetAll(series1, map[string][]int64{})
+	assertTotals(series1, []string{"A"}, []int64{0})
+
+	series1.Record(ctx, vc, "A", 2)
+	series1.Record(ctx, vc, "B", 5)
+
+	setNow(time.Date(2021, 11, 18, 12, 16, 3, 234567, time.UTC)) // move forward 2 intervals
+
+	series1.Record(ctx, vc, "A", 3)
+	series1.Record(ctx, vc, "C", 1)
+
+	assertGetMany(series1, []string{"A", "B", "C", "D"}, [][]int64{{3, 0, 2}, {0, 0, 5}, {1, 0, 0}, {0, 0, 0}})
+	assertGetAll(series1, map[string][]int64{"A": {3, 0, 2}, "B": {0, 0, 5}, "C": {1, 0, 0}})
+	assertTotals(series1, []string{"C", "A", "B", "D"}, []int64{1, 5, 5, 0})
+
+	setNow(time.Date(2021, 11, 18, 12, 21, 3, 234567, time.UTC)) // move forward 1 interval
+
+	assertGetMany(series1, []string{"A", "B"}, [][]int64{{0, 3, 0}, {0, 0, 0}})
+	assertGetAll(series1, map[string][]int64{"A": {0, 3, 0}, "C": {0, 1, 0}})
+	assertTotals(series1, []string{"A", "B", "C"}, []int64{3, 0, 1})
+}
+
+func TestIntervalSeriesWindowEstimates(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertEstimates := func(s *vkutil.IntervalSeries, f string, expectedTotal, expectedRate, expectedAverage float64) {
+		total, err := s.WeightedTotal(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.InDelta(t, expectedTotal, total, 0.0001, "weighted total mismatch")
+
+		rate, err := s.Rate(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.InDelta(t, expectedRate, rate, 0.0001, "rate mismatch")
+
+		average, err := s.Average(ctx, vc, f)
+		assert.NoError(t, err)
+		assert.InDelta(t, expectedAverage, average, 0.0001, "average mismatch")
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 1, 0, 0, time.UTC))
+
+	// create a 5 minute x 3 based series, so window is 10 minutes
+	series1 := vkutil.NewIntervalSeries("foos", time.Minute*5, 3)
+	assertEstimates(series1, "A", 0, 0, 0)
+
+	series1.Record(ctx, vc, "A", 60)
+
+	setNow(time.Date(2021, 11, 18, 12, 6, 0, 0, time.UTC))
+
+	series1.Record(ctx, vc, "A", 120)
+
+	assertEstimates(series1, "A", 180, 0.3, 90)
+
+	// at the very start of the next interval, oldest interval counts fully
+	setNow(time.Date(2021, 11, 18, 12, 10, 0, 0, time.UTC))
+
+	assertEstimates(series1, "A", 180, 0.3, 90)
+
+	// a fifth of the way into the interval, oldest interval is weighted by 0.8
+	setNow(time.Date(2021, 11, 18, 12, 11, 0, 0, time.UTC))
+
+	series1.Record(ctx, vc, "A", 30)
+
+	assertEstimates(series1, "A", 198, 0.33, 99)
+
+	// create a 1 minute x 1 based series, so window is the elapsed part of the current interval
+	setNow(time.Date(2021, 11, 18, 12, 11, 0, 0, time.UTC))
+
+	series2 := vkutil.NewIntervalSeries("bars", time.Minute, 1)
+	series2.Record(ctx, vc, "A", 30)
+
+	assertEstimates(series2, "A", 30, 0, 0)
+
+	setNow(time.Date(2021, 11, 18, 12, 11, 15, 0, time.UTC))
+
+	assertEstimates(series2, "A", 30, 2, 120)
+
+	// check the clock is only read once, even if time crosses an interval boundary during the call
+	calls := 0
+	vkutil.SetNow(func() time.Time {
+		calls++
+		if calls == 1 {
+			return time.Date(2021, 11, 18, 12, 14, 59, 0, time.UTC)
+		}
+		return time.Date(2021, 11, 18, 12, 15, 0, 0, time.UTC)
+	})
+
+	total, err := series1.WeightedTotal(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.InDelta(t, 150.2, total, 0.0001)
+}
+
+func TestIntervalSeriesTopN(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertTopN := func(s *vkutil.IntervalSeries, n int, expectedFields []string, expectedTotals []int64) {
+		actualFields, actualTotals, err := s.TopN(ctx, vc, n)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedFields, actualFields, "top %d fields mismatch", n)
+		assert.Equal(t, expectedTotals, actualTotals, "top %d totals mismatch", n)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	series1 := vkutil.NewIntervalSeries("foos", time.Minute*5, 2)
+	assertTopN(series1, 3, []string{}, []int64{})
+
+	series1.RecordMany(ctx, vc, map[string]int64{"A": 5, "B": 2, "C": 8, "D": 1})
+
+	setNow(time.Date(2021, 11, 18, 12, 11, 3, 234567, time.UTC))
+
+	series1.RecordMany(ctx, vc, map[string]int64{"A": 4, "B": 7, "E": 3})
+
+	assertTopN(series1, 3, []string{"A", "B", "C"}, []int64{9, 9, 8})
+	assertTopN(series1, 1, []string{"A"}, []int64{9})
+	assertTopN(series1, 10, []string{"A", "B", "C", "E", "D"}, []int64{9, 9, 8, 3, 1})
+	assertTopN(series1, 0, []string{}, []int64{})
+
+	setNow(time.Date(2021, 11, 18, 12, 16, 3, 234567, time.UTC))
+
+	assertTopN(series1, 2, []string{"B", "A"}, []int64{7, 4})
+}
+
+func TestIntervalSeriesPoints(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	t1205 := time.Date(2021, 11, 18, 12, 5, 0, 0, time.UTC)
+	t1210 := time.Date(2021, 11, 18, 12, 10, 0, 0, time.UTC)
+	t1215 := time.Date(2021, 11, 18, 12, 15, 0, 0, time.UTC)
+	t1220 := time.Date(2021, 11, 18, 12, 20, 0, 0, time.UTC)
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	series1 := vkutil.NewIntervalSeries("foos", time.Minute*5, 3)
+	series1.Record(ctx, vc, "A", 2)
+
+	setNow(time.Date(2021, 11, 18, 12, 16, 3, 234567, time.UTC))
+
+	series1.Record(ctx, vc, "A", 5)
+
+	points, err := series1.GetPoints(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{{t1215, 5}, {t1210, 0}, {t1205, 2}}, points)
+
+	points, err = series1.Range(ctx, vc, "A", t1210, t1215)
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{{t1210, 0}}, points)
+
+	points, err = series1.Range(ctx, vc, "A", t1205.Add(time.Minute), t1215.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{{t1215, 5}, {t1210, 0}, {t1205, 2}}, points)
+
+	points, err = series1.Range(ctx, vc, "A", t1220, t1220.Add(time.Hour)) // in the future
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{}, points)
+
+	points, err = series1.Range(ctx, vc, "B", t1205, t1220)
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{{t1215, 0}, {t1210, 0}, {t1205, 0}}, points)
+}
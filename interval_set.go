@@ -3,6 +3,7 @@ package vkutil
 import (
 	"context"
 	_ "embed"
+	"iter"
 	"time"
 
 	valkey "github.com/gomodule/redigo/redis"
@@ -10,14 +11,12 @@ import (
 
 // IntervalSet operates like a set but with expiring intervals
 type IntervalSet struct {
-	keyBase  string
-	interval time.Duration // e.g. 5 minutes
-	size     int           // number of intervals
+	intervals
 }
 
 // NewIntervalSet creates a new empty interval set
-func NewIntervalSet(keyBase string, interval time.Duration, size int) *IntervalSet {
-	return &IntervalSet{keyBase: keyBase, interval: interval, size: size}
+func NewIntervalSet(keyBase string, interval time.Duration, size int, opts ...IntervalOption) *IntervalSet {
+	return &IntervalSet{intervals: newIntervals(keyBase, interval, size, opts)}
 }
 
 //go:embed lua/iset_ismember.lua
@@ -31,17 +30,120 @@ func (s *IntervalSet) IsMember(ctx context.Context, vc valkey.Conn, member strin
 	return valkey.Bool(isetIsMemberScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(member)...))
 }
 
+//go:embed lua/iset_aremembers.lua
+var isetAreMembers string
+var isetAreMembersScript = valkey.NewScript(-1, isetAreMembers)
+
+// AreMembers returns whether we contain each of the given values
+func (s *IntervalSet) AreMembers(ctx context.Context, vc valkey.Conn, members ...string) ([]bool, error) {
+	if len(members) == 0 {
+		return []bool{}, nil
+	}
+
+	keys := s.keys()
+
+	found, err := valkey.Ints(isetAreMembersScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys).AddFlat(members)...))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(found))
+	for i, f := range found {
+		results[i] = f == 1
+	}
+	return results, nil
+}
+
 // Add adds the given value
 func (s *IntervalSet) Add(ctx context.Context, vc valkey.Conn, member string) error {
+	return s.AddMany(ctx, vc, member)
+}
+
+// AddMany adds the given values
+func (s *IntervalSet) AddMany(ctx context.Context, vc valkey.Conn, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
 	key := s.keys()[0]
 
 	vc.Send("MULTI")
-	vc.Send("SADD", key, member)
+	vc.Send("SADD", valkey.Args{}.Add(key).AddFlat(members)...)
 	vc.Send("EXPIRE", key, s.size*int(s.interval/time.Second))
 	_, err := valkey.DoContext(vc, ctx, "EXEC")
 	return err
 }
 
+// AddIfNew atomically adds the given value if it isn't already a member, returning whether it was added
+func (s *IntervalSet) AddIfNew(ctx context.Context, vc valkey.Conn, member string) (bool, error) {
+	added, err := s.AddManyIfNew(ctx, vc, member)
+	if err != nil {
+		return false, err
+	}
+	return added[0], nil
+}
+
+//go:embed lua/iset_addifnew.lua
+var isetAddIfNew string
+var isetAddIfNewScript = valkey.NewScript(-1, isetAddIfNew)
+
+// AddManyIfNew atomically adds each of the given values which aren't already members, returning whether each was
+// added. If a value is repeated, only its first occurrence is considered new.
+func (s *IntervalSet) AddManyIfNew(ctx context.Context, vc valkey.Conn, members ...string) ([]bool, error) {
+	if len(members) == 0 {
+		return []bool{}, nil
+	}
+
+	keys := s.keys()
+	args := valkey.Args{}.Add(len(keys)).AddFlat(keys).Add(s.size * int(s.interval/time.Second)).AddFlat(members)
+
+	added, err := valkey.Ints(isetAddIfNewScript.DoContext(ctx, vc, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]bool, len(added))
+	for i, a := range added {
+		results[i] = a == 1
+	}
+	return results, nil
+}
+
+// Members returns all values across all intervals
+func (s *IntervalSet) Members(ctx context.Context, vc valkey.Conn) ([]string, error) {
+	return valkey.Strings(valkey.DoContext(vc, ctx, "SUNION", valkey.Args{}.AddFlat(s.keys())...))
+}
+
+//go:embed lua/iset_card.lua
+var isetCard string
+var isetCardScript = valkey.NewScript(-1, isetCard)
+
+// Card returns the exact number of distinct values across all intervals
+func (s *IntervalSet) Card(ctx context.Context, vc valkey.Conn) (int, error) {
+	keys := s.keys()
+
+	return valkey.Int(isetCardScript.DoContext(ctx, vc, valkey.Args{}.Add(len(keys)).AddFlat(keys)...))
+}
+
+// CardEstimate returns the sum of the cardinalities of each interval, which is cheaper than Card but will over count
+// values which are members of more than one interval
+func (s *IntervalSet) CardEstimate(ctx context.Context, vc valkey.Conn) (int, error) {
+	vc.Send("MULTI")
+	for _, k := range s.keys() {
+		vc.Send("SCARD", k)
+	}
+	cards, err := valkey.Ints(valkey.DoContext(vc, ctx, "EXEC"))
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, c := range cards {
+		total += c
+	}
+	return total, nil
+}
+
 // Rem removes the given values
 func (s *IntervalSet) Rem(ctx context.Context, vc valkey.Conn, members ...string) error {
 	vc.Send("MULTI")
@@ -62,6 +164,38 @@ func (s *IntervalSet) Clear(ctx context.Context, vc valkey.Conn) error {
 	return err
 }
 
-func (s *IntervalSet) keys() []string {
-	return intervalKeys(s.keyBase, s.interval, s.size)
+// Scan returns an iterator over all members across all intervals, using SSCAN so that large sets don't need to be
+// loaded into memory at once. Members found in multiple intervals are only returned once, which is checked against the
+// newer intervals on the server rather than by tracking returned members. As with SSCAN itself, a member which is added
+// or removed during iteration may or may not be returned, and a member may be returned more than once if its interval
+// is rehashed during iteration. If an error occurs it is yielded with an empty member and iteration stops.
+func (s *IntervalSet) Scan(ctx context.Context, vc valkey.Conn, opts ...ScanOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		keys := s.keys()
+
+		err := scanKeys(ctx, vc, "SSCAN", keys, newScanOptions(opts), func(i int, members []string) (bool, error) {
+			var inNewer []int
+
+			// members which exist in a newer interval have already been returned
+			if i > 0 {
+				var err error
+				inNewer, err = valkey.Ints(isetAreMembersScript.DoContext(ctx, vc, valkey.Args{}.Add(i).AddFlat(keys[:i]).AddFlat(members)...))
+				if err != nil {
+					return false, err
+				}
+			}
+
+			for j, m := range members {
+				if inNewer == nil || inNewer[j] == 0 {
+					if !yield(m, nil) {
+						return false, nil
+					}
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			yield("", err)
+		}
+	}
 }
@@ -2,6 +2,7 @@ package vkutil_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -133,3 +134,218 @@ func TestIntervalSet(t *testing.T) {
 	assertIsMember(set3, "B")
 	assertNotIsMember(set3, "C")
 }
+
+func TestIntervalSetScan(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	scanAll := func(s *vkutil.IntervalSet, opts ...vkutil.ScanOption) []string {
+		members := []string{}
+		for m, err := range s.Scan(ctx, vc, opts...) {
+			require.NoError(t, err)
+			members = append(members, m)
+		}
+		return members
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 0, 3, 234567, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2)
+	assert.Equal(t, []string{}, scanAll(set1))
+
+	set1.Add(ctx, vc, "A1")
+	set1.Add(ctx, vc, "A2")
+	set1.Add(ctx, vc, "B1")
+
+	setNow(time.Date(2021, 11, 19, 12, 0, 3, 234567, time.UTC))
+
+	set1.Add(ctx, vc, "A1")
+	set1.Add(ctx, vc, "B2")
+
+	assert.ElementsMatch(t, []string{"A1", "A2", "B1", "B2"}, scanAll(set1))
+	assert.ElementsMatch(t, []string{"A1", "A2", "B1", "B2"}, scanAll(set1, vkutil.WithCount(1)))
+	assert.ElementsMatch(t, []string{"A1", "A2"}, scanAll(set1, vkutil.WithMatch("A*")))
+
+	// check we can stop iteration early
+	n := 0
+	for range set1.Scan(ctx, vc) {
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+
+	// errors are yielded
+	vc.Do("SET", "{bars}:2021-11-19", "x")
+
+	set2 := vkutil.NewIntervalSet("bars", time.Hour*24, 2)
+	var scanErr error
+	for _, err := range set2.Scan(ctx, vc) {
+		scanErr = err
+	}
+	assert.ErrorContains(t, scanErr, "WRONGTYPE")
+
+	setNow(time.Date(2021, 11, 20, 12, 0, 3, 234567, time.UTC))
+
+	assert.ElementsMatch(t, []string{"A1", "B2"}, scanAll(set1))
+}
+
+func TestIntervalSetMany(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertAreMembers := func(s *vkutil.IntervalSet, members []string, expected []bool) {
+		actual, err := s.AreMembers(ctx, vc, members...)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "are members mismatch for %v", members)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 0, 3, 234567, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2)
+	assert.NoError(t, set1.AddMany(ctx, vc, "A", "B", "C"))
+	assert.NoError(t, set1.AddMany(ctx, vc)) // noop
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-18", []string{"A", "B", "C"})
+
+	assertAreMembers(set1, []string{"A", "D", "C"}, []bool{true, false, true})
+	assertAreMembers(set1, []string{}, []bool{})
+
+	setNow(time.Date(2021, 11, 19, 12, 0, 3, 234567, time.UTC))
+
+	assert.NoError(t, set1.AddMany(ctx, vc, "D", "E"))
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-19", []string{"D", "E"})
+
+	assertAreMembers(set1, []string{"A", "B", "C", "D", "E", "F"}, []bool{true, true, true, true, true, false})
+
+	setNow(time.Date(2021, 11, 20, 12, 0, 3, 234567, time.UTC))
+
+	assertAreMembers(set1, []string{"A", "B", "C", "D", "E", "F"}, []bool{false, false, false, true, true, false})
+
+	// check large batches which require unpacking in chunks
+	many := make([]string, 2500)
+	for i := range many {
+		many[i] = fmt.Sprintf("M%d", i)
+	}
+	assert.NoError(t, set1.AddMany(ctx, vc, many[:2000]...))
+
+	found, err := set1.AreMembers(ctx, vc, many...)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2500)
+	assert.True(t, found[0])
+	assert.True(t, found[1999])
+	assert.False(t, found[2000])
+	assert.False(t, found[2499])
+}
+
+func TestIntervalSetAddIfNew(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertAddIfNew := func(s *vkutil.IntervalSet, member string, expected bool) {
+		added, err := s.AddIfNew(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, added, "add if new mismatch for %s", member)
+	}
+	assertAddManyIfNew := func(s *vkutil.IntervalSet, members []string, expected []bool) {
+		added, err := s.AddManyIfNew(ctx, vc, members...)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, added, "add many if new mismatch for %v", members)
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 0, 3, 234567, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2)
+	assertAddIfNew(set1, "A", true)
+	assertAddIfNew(set1, "A", false)
+	assertAddManyIfNew(set1, []string{"A", "B", "C", "B"}, []bool{false, true, true, false})
+	assertAddManyIfNew(set1, []string{}, []bool{})
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-18", []string{"A", "B", "C"})
+
+	setNow(time.Date(2021, 11, 19, 12, 0, 3, 234567, time.UTC))
+
+	// members in the previous interval aren't re-added to the current interval
+	assertAddManyIfNew(set1, []string{"C", "D", "A"}, []bool{false, true, false})
+	assertAddIfNew(set1, "D", false)
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-19", []string{"D"})
+	assertvk.SMembers(t, vc, "{foos}:2021-11-18", []string{"A", "B", "C"})
+
+	setNow(time.Date(2021, 11, 20, 12, 0, 3, 234567, time.UTC))
+
+	assertAddManyIfNew(set1, []string{"A", "D"}, []bool{true, false})
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-20", []string{"A"})
+}
+
+func TestIntervalSetMembersAndCard(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertMembers := func(s *vkutil.IntervalSet, expected []string) {
+		actual, err := s.Members(ctx, vc)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, actual)
+	}
+	assertCard := func(s *vkutil.IntervalSet, expected, expectedEstimate int) {
+		actual, err := s.Card(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "card mismatch")
+
+		actual, err = s.CardEstimate(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expectedEstimate, actual, "card estimate mismatch")
+	}
+
+	setNow(time.Date(2021, 11, 18, 12, 0, 3, 234567, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2)
+	assertMembers(set1, []string{})
+	assertCard(set1, 0, 0)
+
+	set1.AddMany(ctx, vc, "A", "B", "C")
+
+	assertMembers(set1, []string{"A", "B", "C"})
+	assertCard(set1, 3, 3)
+
+	setNow(time.Date(2021, 11, 19, 12, 0, 3, 234567, time.UTC))
+
+	set1.AddMany(ctx, vc, "B", "D")
+
+	assertMembers(set1, []string{"A", "B", "C", "D"})
+	assertCard(set1, 4, 5)
+
+	setNow(time.Date(2021, 11, 20, 12, 0, 3, 234567, time.UTC))
+
+	assertMembers(set1, []string{"B", "D"})
+	assertCard(set1, 2, 2)
+}
@@ -0,0 +1,187 @@
+package vkutil
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock is a source of the current time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return timeNow() }
+
+// IntervalOption is an option for an interval based struct
+type IntervalOption func(*intervalOptions)
+
+type intervalOptions struct {
+	location  *time.Location
+	offset    time.Duration
+	formatter KeyFormatter
+	clock     Clock
+}
+
+func newIntervalOptions(opts []IntervalOption) intervalOptions {
+	o := intervalOptions{location: time.UTC, formatter: TimestampKeys, clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock configures the source of the current time, e.g. to control time in tests
+func WithClock(c Clock) IntervalOption {
+	return func(o *intervalOptions) { o.clock = c }
+}
+
+// WithLocation configures the timezone in which intervals of a day or longer are aligned, e.g. so that daily intervals
+// start at local midnight, and their keys are based on the local date. Such intervals are an hour shorter or longer
+// across DST transitions. Shorter intervals are always aligned on absolute time and keyed in UTC, so that the repeated
+// hour at the end of DST doesn't share a key, and their start times are only converted to this location.
+func WithLocation(loc *time.Location) IntervalOption {
+	return func(o *intervalOptions) { o.location = loc }
+}
+
+// WithOffset configures an offset for interval boundaries, e.g. 6 hours so that daily intervals start at 06:00
+func WithOffset(offset time.Duration) IntervalOption {
+	return func(o *intervalOptions) { o.offset = offset }
+}
+
+// WithKeyFormatter configures how the keys of intervals are formatted
+func WithKeyFormatter(f KeyFormatter) IntervalOption {
+	return func(o *intervalOptions) { o.formatter = f }
+}
+
+// KeyFormatter formats the key of an interval from the key base and the start time and length of the interval. The
+// start time is always in UTC, and for intervals of a day or longer, is the local wall clock time of the start. To
+// support cluster mode, keys should use the key base as a hashtag, i.e. {keyBase}.
+type KeyFormatter interface {
+	Format(keyBase string, start time.Time, interval time.Duration) string
+}
+
+// KeyFormatterFunc is a function which can be used as a KeyFormatter
+type KeyFormatterFunc func(keyBase string, start time.Time, interval time.Duration) string
+
+// Format formats a key by calling the function
+func (f KeyFormatterFunc) Format(keyBase string, start time.Time, interval time.Duration) string {
+	return f(keyBase, start, interval)
+}
+
+// TimestampKeys is the default key formatter which gives keys like {keyBase}:2006-01-02T15:04, using a precision
+// based on the interval length. Intervals which aren't a whole number of that precision (e.g. 36 hours) can't be
+// represented unambiguously by a timestamp so use the same format as EpochKeys.
+var TimestampKeys KeyFormatter = KeyFormatterFunc(func(keyBase string, start time.Time, interval time.Duration) string {
+	var layout string
+	var precision time.Duration
+	if interval < time.Minute {
+		layout, precision = "2006-01-02T15:04:05", time.Second
+	} else if interval < time.Hour*24 {
+		layout, precision = "2006-01-02T15:04", time.Minute
+	} else {
+		layout, precision = "2006-01-02", time.Hour*24
+	}
+
+	if interval%precision != 0 {
+		return EpochKeys.Format(keyBase, start, interval)
+	}
+	return fmt.Sprintf("{%s}:%s", keyBase, start.Format(layout))
+})
+
+// EpochKeys is a compact key formatter which gives keys like {keyBase}:18949, using the number of the interval length
+// bucket since the unix epoch which contains the start of the interval. Intervals are aligned from the zero time, so
+// that e.g. weekly intervals start on Mondays, so the bucket matches the span of the interval when the interval evenly
+// divides a day, but not necessarily otherwise (e.g. 36 hours or 7 days).
+var EpochKeys KeyFormatter = KeyFormatterFunc(func(keyBase string, start time.Time, interval time.Duration) string {
+	seconds := int64(interval / time.Second)
+	bucket := start.Unix() / seconds
+	if start.Unix()%seconds < 0 {
+		bucket-- // round towards negative infinity for times before the epoch
+	}
+	return fmt.Sprintf("{%s}:%d", keyBase, bucket)
+})
+
+// intervals is the key space shared by all interval based structs
+type intervals struct {
+	keyBase  string
+	interval time.Duration // e.g. 5 minutes
+	size     int           // number of intervals
+
+	intervalOptions
+}
+
+// newIntervals creates a new key space, panicking if the interval, size or location are invalid, as these are
+// programming errors
+func newIntervals(keyBase string, interval time.Duration, size int, opts []IntervalOption) intervals {
+	if interval < time.Second || interval%time.Second != 0 {
+		panic(fmt.Sprintf("interval must be a positive whole number of seconds, got %s", interval))
+	}
+	if size < 1 {
+		panic(fmt.Sprintf("size must be at least 1, got %d", size))
+	}
+
+	o := newIntervalOptions(opts)
+	if o.location == nil {
+		panic("location can't be nil")
+	}
+
+	return intervals{keyBase: keyBase, interval: interval, size: size, intervalOptions: o}
+}
+
+// keys returns the keys of all intervals, from the current interval to the oldest
+func (i *intervals) keys() []string {
+	return i.keysAt(i.clock.Now())
+}
+
+func (i *intervals) keysAt(now time.Time) []string {
+	start := i.wallStart(now)
+	keys := make([]string, i.size)
+	for n := range keys {
+		keys[n] = i.formatter.Format(i.keyBase, start.Add(-i.interval*time.Duration(n)), i.interval)
+	}
+	return keys
+}
+
+// start returns the start of the interval containing the given time
+func (i *intervals) start(t time.Time) time.Time {
+	return i.fromWall(i.wallStart(t))
+}
+
+// progress returns how far through its interval the given time is, as a fraction between 0 and 1
+func (i *intervals) progress(t time.Time) float64 {
+	return float64(i.toWall(t).Sub(i.wallStart(t))) / float64(i.interval)
+}
+
+// wallStart returns the start of the interval containing the given time, as the time used for alignment and keys
+func (i *intervals) wallStart(t time.Time) time.Time {
+	return i.toWall(t).Add(-i.offset).Truncate(i.interval).Add(i.offset)
+}
+
+// onWallClock returns whether intervals are aligned on wall clock time in our location rather than absolute time.
+// This is only done for intervals of at least a day, as shorter intervals would otherwise merge the repeated hour at
+// the end of DST into one interval.
+func (i *intervals) onWallClock() bool {
+	return i.interval >= time.Hour*24
+}
+
+// toWall converts the given time to the time used for alignment and keys, which is either a UTC time with the same
+// wall clock time as in our location, or just the time in UTC
+func (i *intervals) toWall(t time.Time) time.Time {
+	if !i.onWallClock() {
+		return t.UTC()
+	}
+
+	t = t.In(i.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// fromWall converts the given time used for alignment and keys back to a time in our location
+func (i *intervals) fromWall(w time.Time) time.Time {
+	if !i.onWallClock() {
+		return w.In(i.location)
+	}
+
+	return time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), w.Nanosecond(), i.location)
+}
@@ -0,0 +1,255 @@
+package vkutil_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalAlignment(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// daily intervals in a timezone roll over at local midnight
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2, vkutil.WithLocation(newYork))
+
+	setNow(time.Date(2021, 11, 18, 3, 0, 0, 0, time.UTC)) // 2021-11-17 22:00 in New York
+	set1.Add(ctx, vc, "A")
+
+	setNow(time.Date(2021, 11, 18, 5, 0, 0, 0, time.UTC)) // 2021-11-18 00:00 in New York
+	set1.Add(ctx, vc, "B")
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-17", []string{"A"})
+	assertvk.SMembers(t, vc, "{foos}:2021-11-18", []string{"B"})
+
+	// daily intervals with an offset roll over at that time of day
+	set2 := vkutil.NewIntervalSet("bars", time.Hour*24, 2, vkutil.WithOffset(time.Hour*6))
+
+	setNow(time.Date(2021, 11, 18, 5, 0, 0, 0, time.UTC))
+	set2.Add(ctx, vc, "A")
+
+	setNow(time.Date(2021, 11, 18, 7, 0, 0, 0, time.UTC))
+	set2.Add(ctx, vc, "B")
+
+	assertvk.SMembers(t, vc, "{bars}:2021-11-17", []string{"A"})
+	assertvk.SMembers(t, vc, "{bars}:2021-11-18", []string{"B"})
+
+	// hourly intervals in a timezone across the end of DST, where 01:00-02:00 happens twice, are aligned on absolute
+	// time and keyed in UTC so that each real hour has its own interval
+	series1 := vkutil.NewIntervalSeries("bazs", time.Hour, 4, vkutil.WithLocation(newYork))
+
+	setNow(time.Date(2021, 11, 7, 5, 30, 0, 0, time.UTC)) // 01:30 EDT
+	series1.Record(ctx, vc, "A", 1)
+
+	setNow(time.Date(2021, 11, 7, 6, 30, 0, 0, time.UTC)) // 01:30 EST
+	series1.Record(ctx, vc, "A", 2)
+
+	setNow(time.Date(2021, 11, 7, 7, 30, 0, 0, time.UTC)) // 02:30 EST
+	series1.Record(ctx, vc, "A", 4)
+
+	assertvk.HGetAll(t, vc, "{bazs}:2021-11-07T05:00", map[string]string{"A": "1"})
+	assertvk.HGetAll(t, vc, "{bazs}:2021-11-07T06:00", map[string]string{"A": "2"})
+	assertvk.HGetAll(t, vc, "{bazs}:2021-11-07T07:00", map[string]string{"A": "4"})
+
+	points, err := series1.GetPoints(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{
+		{Start: time.Date(2021, 11, 7, 7, 0, 0, 0, time.UTC).In(newYork), Value: 4}, // 02:00 EST
+		{Start: time.Date(2021, 11, 7, 6, 0, 0, 0, time.UTC).In(newYork), Value: 2}, // 01:00 EST
+		{Start: time.Date(2021, 11, 7, 5, 0, 0, 0, time.UTC).In(newYork), Value: 1}, // 01:00 EDT
+		{Start: time.Date(2021, 11, 7, 4, 0, 0, 0, time.UTC).In(newYork), Value: 0}, // 00:00 EDT
+	}, points)
+
+	// and across the start of DST, where 02:00-03:00 doesn't happen
+	series2 := vkutil.NewIntervalSeries("quxs", time.Hour, 3, vkutil.WithLocation(newYork))
+
+	setNow(time.Date(2021, 3, 14, 6, 30, 0, 0, time.UTC)) // 01:30 EST
+	series2.Record(ctx, vc, "A", 1)
+
+	setNow(time.Date(2021, 3, 14, 7, 30, 0, 0, time.UTC)) // 03:30 EDT
+	series2.Record(ctx, vc, "A", 2)
+
+	assertvk.HGetAll(t, vc, "{quxs}:2021-03-14T06:00", map[string]string{"A": "1"})
+	assertvk.HGetAll(t, vc, "{quxs}:2021-03-14T07:00", map[string]string{"A": "2"})
+
+	vals, err := series2.Get(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1, 0}, vals)
+}
+
+func TestIntervalKeyFormatters(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*24, 2, vkutil.WithKeyFormatter(vkutil.EpochKeys))
+	set1.Add(ctx, vc, "A")
+
+	hash1 := vkutil.NewIntervalHash("bars", time.Minute*5, 2, vkutil.WithKeyFormatter(vkutil.EpochKeys))
+	hash1.Set(ctx, vc, "A", "1")
+
+	custom := vkutil.KeyFormatterFunc(func(keyBase string, start time.Time, interval time.Duration) string {
+		return fmt.Sprintf("{%s}/%s/%s", keyBase, interval, start.Format("20060102150405"))
+	})
+	series1 := vkutil.NewIntervalSeries("bazs", time.Hour, 2, vkutil.WithKeyFormatter(custom))
+	series1.Record(ctx, vc, "A", 3)
+
+	assertvk.SMembers(t, vc, "{foos}:18949", []string{"A"})
+	assertvk.HGetAll(t, vc, "{bars}:5457457", map[string]string{"A": "1"})
+	assertvk.HGetAll(t, vc, "{bazs}/1h0m0s/20211118120000", map[string]string{"A": "3"})
+
+	setNow(time.Date(2021, 11, 19, 12, 7, 3, 234567, time.UTC))
+
+	set1.Add(ctx, vc, "B")
+
+	assertvk.SMembers(t, vc, "{foos}:18950", []string{"B"})
+
+	members, err := set1.Members(ctx, vc)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A", "B"}, members)
+
+	// default formatter is timestamps
+	assert.Equal(t, "{foos}:2021-11-18T12:05", vkutil.TimestampKeys.Format("foos", time.Date(2021, 11, 18, 12, 5, 0, 0, time.UTC), time.Minute*5))
+}
+
+func TestNonCalendarIntervals(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	// intervals which aren't a whole number of days, minutes or seconds use epoch bucket numbers
+	set1 := vkutil.NewIntervalSet("foos", time.Hour*36, 2)
+	set1.Add(ctx, vc, "A")
+
+	set2 := vkutil.NewIntervalSet("bars", time.Second*90, 2)
+	set2.Add(ctx, vc, "A")
+
+	set3 := vkutil.NewIntervalSet("bazs", time.Minute*90, 2)
+	set3.Add(ctx, vc, "A")
+
+	assertvk.SMembers(t, vc, "{foos}:12632", []string{"A"})
+	assertvk.SMembers(t, vc, "{bars}:18191524", []string{"A"})
+	assertvk.SMembers(t, vc, "{bazs}:2021-11-18T12:00", []string{"A"})
+
+	// a key's bucket is the one containing the start of its interval, which for 36h intervals isn't the same span
+	series1 := vkutil.NewIntervalSeries("quxs", time.Hour*36, 2)
+	series1.Record(ctx, vc, "A", 1)
+
+	assertvk.HGetAll(t, vc, "{quxs}:12632", map[string]string{"A": "1"})
+
+	points, err := series1.GetPoints(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []vkutil.Point{
+		{Start: time.Date(2021, 11, 18, 0, 0, 0, 0, time.UTC), Value: 1},
+		{Start: time.Date(2021, 11, 16, 12, 0, 0, 0, time.UTC), Value: 0},
+	}, points)
+
+	setNow(time.Date(2021, 11, 18, 12, 8, 33, 234567, time.UTC))
+
+	set2.Add(ctx, vc, "B")
+
+	assertvk.SMembers(t, vc, "{bars}:18191525", []string{"B"})
+
+	members, err := set2.Members(ctx, vc)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A", "B"}, members)
+
+	// intervals which would give ambiguous keys or expirations are rejected
+	assert.PanicsWithValue(t, "interval must be a positive whole number of seconds, got 500ms", func() {
+		vkutil.NewIntervalSet("foos", time.Millisecond*500, 2)
+	})
+	assert.PanicsWithValue(t, "interval must be a positive whole number of seconds, got 1.5s", func() {
+		vkutil.NewIntervalHash("foos", time.Millisecond*1500, 2)
+	})
+	assert.PanicsWithValue(t, "interval must be a positive whole number of seconds, got 0s", func() {
+		vkutil.NewIntervalSeries("foos", 0, 2)
+	})
+	assert.PanicsWithValue(t, "size must be at least 1, got 0", func() {
+		vkutil.NewIntervalSet("foos", time.Hour, 0)
+	})
+	assert.PanicsWithValue(t, "location can't be nil", func() {
+		vkutil.NewIntervalSet("foos", time.Hour*24, 2, vkutil.WithLocation(nil))
+	})
+}
+
+func TestIntervalClock(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	clock := assertvk.NewFakeClock(time.Date(2021, 11, 18, 12, 7, 3, 0, time.UTC))
+
+	set1 := vkutil.NewIntervalSet("foos", time.Hour, 2, vkutil.WithClock(clock))
+	hash1 := vkutil.NewIntervalHash("bars", time.Hour, 2, vkutil.WithClock(clock))
+	series1 := vkutil.NewIntervalSeries("bazs", time.Hour, 2, vkutil.WithClock(clock))
+	sliding1 := vkutil.NewSlidingSet("quxs", time.Hour, vkutil.WithSlidingClock(clock))
+
+	set1.Add(ctx, vc, "A")
+	hash1.Set(ctx, vc, "A", "1")
+	series1.Record(ctx, vc, "A", 1)
+	sliding1.Add(ctx, vc, "A")
+
+	assertvk.SMembers(t, vc, "{foos}:2021-11-18T12:00", []string{"A"})
+	assertvk.HGetAll(t, vc, "{bars}:2021-11-18T12:00", map[string]string{"A": "1"})
+	assertvk.HGetAll(t, vc, "{bazs}:2021-11-18T12:00", map[string]string{"A": "1"})
+
+	clock.Advance(time.Hour)
+
+	series1.Record(ctx, vc, "A", 2)
+
+	assertvk.HGetAll(t, vc, "{bazs}:2021-11-18T13:00", map[string]string{"A": "2"})
+
+	vals, err := series1.Get(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, vals)
+
+	clock.Advance(time.Hour)
+
+	isMember, err := set1.IsMember(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+
+	value, err := hash1.Get(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "", value)
+
+	isMember, err = sliding1.IsMember(ctx, vc, "A")
+	assert.NoError(t, err)
+	assert.False(t, isMember)
+}
@@ -0,0 +1,13 @@
+local dest, op, expire = KEYS[1], ARGV[1], tonumber(ARGV[2])
+
+redis.call("BITOP", op, dest, unpack(KEYS, 2))
+local count = redis.call("BITCOUNT", dest)
+
+-- if no expiry was given then dest was only needed to count the result
+if (expire > 0) then
+	redis.call("PEXPIRE", dest, expire)
+else
+	redis.call("DEL", dest)
+end
+
+return count
\ No newline at end of file
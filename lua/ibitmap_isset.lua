@@ -0,0 +1,9 @@
+local offset = ARGV[1]
+
+for _, key in ipairs(KEYS) do
+	if (redis.call("GETBIT", key, offset) == 1) then
+		return 1
+	end
+end
+
+return 0
\ No newline at end of file
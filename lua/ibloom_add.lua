@@ -0,0 +1,8 @@
+local expire = ARGV[1]
+
+-- set all the bits for the given members in the current interval
+for i = 2, #ARGV do
+	redis.call("SETBIT", KEYS[1], ARGV[i], 1)
+end
+
+redis.call("EXPIRE", KEYS[1], expire)
\ No newline at end of file
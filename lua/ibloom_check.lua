@@ -0,0 +1,26 @@
+local numHashes = tonumber(ARGV[1])
+local numMembers = (#ARGV - 1) / numHashes
+local results = {}
+
+for m = 1, numMembers do
+	local first = 2 + (m - 1) * numHashes
+	results[m] = 0
+
+	-- a member might be contained if all its bits are set in any one interval
+	for _, key in ipairs(KEYS) do
+		local all = 1
+		for i = first, first + numHashes - 1 do
+			if (redis.call("GETBIT", key, ARGV[i]) == 0) then
+				all = 0
+				break
+			end
+		end
+
+		if (all == 1) then
+			results[m] = 1
+			break
+		end
+	end
+end
+
+return results
\ No newline at end of file
@@ -0,0 +1,17 @@
+local key = KEYS[1]
+local field, value, expire = ARGV[1], ARGV[2], ARGV[3]
+local num = tonumber(value)
+
+local min = redis.call("HGET", key, field .. ":min")
+if (min == false or num < tonumber(min)) then
+	redis.call("HSET", key, field .. ":min", value)
+end
+
+local max = redis.call("HGET", key, field .. ":max")
+if (max == false or num > tonumber(max)) then
+	redis.call("HSET", key, field .. ":max", value)
+end
+
+redis.call("HSET", key, field .. ":last", value)
+redis.call("HINCRBY", key, field .. ":count", 1)
+redis.call("EXPIRE", key, expire)
\ No newline at end of file
@@ -0,0 +1,24 @@
+local fields = ARGV
+local results = {}
+local remaining = #fields
+
+-- initialize our list of results to not found
+for i = 1, #fields do
+	results[i] = 0
+end
+
+for _, key in ipairs(KEYS) do
+	for i, field in ipairs(fields) do
+		if (results[i] == 0 and redis.call("HEXISTS", key, field) == 1) then
+			results[i] = 1
+			remaining = remaining - 1
+		end
+	end
+
+	-- if we've found all fields we don't need to look in older keys
+	if (remaining == 0) then
+		break
+	end
+end
+
+return results
\ No newline at end of file
@@ -0,0 +1,49 @@
+local dst = KEYS[1]
+local values = {}
+local ttl = -2
+local dstIsSource = false
+
+-- merge the source keys, which are ordered oldest first, so that newer values overwrite older ones
+for i = 2, #KEYS do
+	local src = KEYS[i]
+	if (src == dst) then
+		dstIsSource = true
+	end
+
+	local fvs = redis.call("HGETALL", src)
+	for j = 1, #fvs, 2 do
+		values[fvs[j]] = fvs[j + 1]
+	end
+
+	ttl = math.max(ttl, redis.call("PTTL", src))
+end
+
+-- if the new key isn't also an old key then everything in it was written by new writers so takes precedence
+if (not dstIsSource) then
+	local fvs = redis.call("HGETALL", dst)
+	for j = 1, #fvs, 2 do
+		values[fvs[j]] = fvs[j + 1]
+	end
+end
+
+local fvs = {}
+for f, v in pairs(values) do
+	table.insert(fvs, f)
+	table.insert(fvs, v)
+end
+
+if (#fvs == 0) then
+	return 0
+end
+
+-- write in batches to avoid exceeding the maximum number of values that can be unpacked
+for offset = 1, #fvs, 1000 do
+	redis.call("HSET", dst, unpack(fvs, offset, math.min(offset + 999, #fvs)))
+end
+
+-- ensure the new key lives at least as long as the old keys would have
+if (ttl > 0 and redis.call("PTTL", dst) < ttl) then
+	redis.call("PEXPIRE", dst, ttl)
+end
+
+return #fvs / 2
\ No newline at end of file
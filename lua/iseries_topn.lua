@@ -0,0 +1,32 @@
+local n = tonumber(ARGV[1])
+local totals = {}
+
+for _, key in ipairs(KEYS) do
+	local fvs = redis.call("HGETALL", key)
+
+	for i = 1, #fvs, 2 do
+		local field, value = fvs[i], tonumber(fvs[i + 1])
+		totals[field] = (totals[field] or 0) + value
+	end
+end
+
+local sorted = {}
+for field, total in pairs(totals) do
+	table.insert(sorted, {field, total})
+end
+
+-- sort by total descending, then by field ascending to make ties deterministic
+table.sort(sorted, function(a, b)
+	if (a[2] ~= b[2]) then
+		return a[2] > b[2]
+	end
+	return a[1] < b[1]
+end)
+
+local result = {}
+for i = 1, math.min(n, #sorted) do
+	table.insert(result, sorted[i][1])
+	table.insert(result, sorted[i][2])
+end
+
+return result
\ No newline at end of file
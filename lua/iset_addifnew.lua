@@ -0,0 +1,47 @@
+local expire = ARGV[1]
+local found = {}
+local remaining = #ARGV - 1
+
+-- initialize our list of found flags, members start at ARGV[2]
+for i = 1, #ARGV - 1 do
+	found[i] = 0
+end
+
+for _, key in ipairs(KEYS) do
+	-- check in batches to avoid exceeding the maximum number of values that can be unpacked
+	for offset = 2, #ARGV, 1000 do
+		local fs = redis.call("SMISMEMBER", key, unpack(ARGV, offset, math.min(offset + 999, #ARGV)))
+
+		for i, f in ipairs(fs) do
+			local j = offset + i - 2
+			if (f == 1 and found[j] == 0) then
+				found[j] = 1
+				remaining = remaining - 1
+			end
+		end
+	end
+
+	-- if we've found all members we don't need to look in older keys
+	if (remaining == 0) then
+		break
+	end
+end
+
+-- add members which weren't found to the current interval, SADD tells us if they're repeated in this batch
+local added = {}
+local numAdded = 0
+
+for i, f in ipairs(found) do
+	if (f == 0) then
+		added[i] = redis.call("SADD", KEYS[1], ARGV[i + 1])
+		numAdded = numAdded + added[i]
+	else
+		added[i] = 0
+	end
+end
+
+if (numAdded > 0) then
+	redis.call("EXPIRE", KEYS[1], expire)
+end
+
+return added
\ No newline at end of file
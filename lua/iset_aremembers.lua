@@ -0,0 +1,30 @@
+local members = ARGV
+local results = {}
+local remaining = #members
+
+-- initialize our list of results to not found
+for i = 1, #members do
+	results[i] = 0
+end
+
+for _, key in ipairs(KEYS) do
+	-- check in batches to avoid exceeding the maximum number of values that can be unpacked
+	for offset = 1, #members, 1000 do
+		local found = redis.call("SMISMEMBER", key, unpack(members, offset, math.min(offset + 999, #members)))
+
+		for i, f in ipairs(found) do
+			local j = offset + i - 1
+			if (f == 1 and results[j] == 0) then
+				results[j] = 1
+				remaining = remaining - 1
+			end
+		end
+	end
+
+	-- if we've found all members we don't need to look in older keys
+	if (remaining == 0) then
+		break
+	end
+end
+
+return results
\ No newline at end of file
@@ -0,0 +1 @@
+return #redis.call("SUNION", unpack(KEYS))
\ No newline at end of file
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	valkey "github.com/gomodule/redigo/redis"
+	"github.com/nyaruka/vkutil"
+)
+
+// Labels are the labels of a metric sample
+type Labels map[string]string
+
+type collector interface {
+	collect(context.Context, valkey.Conn, *bytes.Buffer) error
+}
+
+// Exporter renders the values of registered interval series and gauges in the Prometheus text exposition format
+type Exporter struct {
+	vp         *valkey.Pool
+	collectors []collector
+}
+
+// NewExporter creates a new exporter which reads values using the given pool
+func NewExporter(vp *valkey.Pool) *Exporter {
+	return &Exporter{vp: vp}
+}
+
+// RegisterSeries registers a series to be exported. For each of the given fields and their labels, the total across
+// all intervals is exported as {name}_window and the value in each interval as {name} with an additional interval
+// label, where 0 is the current interval. Both are gauges rather than counters since they drop as intervals expire.
+// Panics if fields have the same labels or use the interval label.
+func (e *Exporter) RegisterSeries(name, help string, series *vkutil.IntervalSeries, fields map[string]Labels) {
+	checkLabels(name, fields, "interval")
+
+	e.collectors = append(e.collectors, &seriesCollector{name: name, help: help, series: series, fields: fields})
+}
+
+// RegisterGauge registers a gauge to be exported. For each of the given fields and their labels, the min, max and
+// last values across all intervals are exported as {name}_min, {name}_max and {name}_last, and the number of
+// observations as {name}_observations. Fields without any observations only have the number of observations exported.
+// Panics if fields have the same labels.
+func (e *Exporter) RegisterGauge(name, help string, gauge *vkutil.IntervalGauge, fields map[string]Labels) {
+	checkLabels(name, fields)
+
+	e.collectors = append(e.collectors, &gaugeCollector{name: name, help: help, gauge: gauge, fields: fields})
+}
+
+// Write writes the current values of all registered metrics to the given writer. Nothing is written if reading any
+// of the values fails.
+func (e *Exporter) Write(ctx context.Context, w io.Writer) error {
+	b, err := e.render(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.WriteTo(w)
+	return err
+}
+
+// ServeHTTP implements http.Handler so that the exporter can be used to serve a metrics endpoint
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := e.render(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error writing metrics: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	b.WriteTo(w)
+}
+
+// render renders all registered metrics into a buffer so that errors can be reported before anything is written
+func (e *Exporter) render(ctx context.Context) (*bytes.Buffer, error) {
+	vc := e.vp.Get()
+	defer vc.Close()
+
+	b := &bytes.Buffer{}
+
+	for _, c := range e.collectors {
+		if err := c.collect(ctx, vc, b); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+type seriesCollector struct {
+	name   string
+	help   string
+	series *vkutil.IntervalSeries
+	fields map[string]Labels
+}
+
+func (c *seriesCollector) collect(ctx context.Context, vc valkey.Conn, w *bytes.Buffer) error {
+	fields := slices.Sorted(maps.Keys(c.fields))
+
+	values, err := c.series.GetMany(ctx, vc, fields...)
+	if err != nil {
+		return fmt.Errorf("error getting values for series %s: %w", c.name, err)
+	}
+
+	writeHeader(w, c.name+"_window", c.help+" (total across all intervals)")
+	for f, field := range fields {
+		var total int64
+		for _, v := range values[f] {
+			total += v
+		}
+		writeSample(w, c.name+"_window", c.fields[field], strconv.FormatInt(total, 10))
+	}
+
+	writeHeader(w, c.name, c.help)
+	for f, field := range fields {
+		labels := maps.Clone(c.fields[field])
+		if labels == nil {
+			labels = Labels{}
+		}
+
+		for i, v := range values[f] {
+			labels["interval"] = strconv.Itoa(i)
+			writeSample(w, c.name, labels, strconv.FormatInt(v, 10))
+		}
+	}
+
+	return nil
+}
+
+type gaugeCollector struct {
+	name   string
+	help   string
+	gauge  *vkutil.IntervalGauge
+	fields map[string]Labels
+}
+
+func (c *gaugeCollector) collect(ctx context.Context, vc valkey.Conn, w *bytes.Buffer) error {
+	fields := slices.Sorted(maps.Keys(c.fields))
+	stats := make([]vkutil.GaugeStats, len(fields))
+
+	for f, field := range fields {
+		s, err := c.gauge.Aggregate(ctx, vc, field)
+		if err != nil {
+			return fmt.Errorf("error getting values for gauge %s: %w", c.name, err)
+		}
+		stats[f] = s
+	}
+
+	for _, m := range []struct {
+		suffix   string
+		help     string
+		value    func(vkutil.GaugeStats) string
+		observed bool // whether this is only meaningful if there have been observations
+	}{
+		{"_min", "minimum", func(s vkutil.GaugeStats) string { return formatFloat(s.Min) }, true},
+		{"_max", "maximum", func(s vkutil.GaugeStats) string { return formatFloat(s.Max) }, true},
+		{"_last", "last", func(s vkutil.GaugeStats) string { return formatFloat(s.Last) }, true},
+		{"_observations", "number of observations", func(s vkutil.GaugeStats) string { return strconv.FormatInt(s.Count, 10) }, false},
+	} {
+		writeHeader(w, c.name+m.suffix, fmt.Sprintf("%s (%s across all intervals)", c.help, m.help))
+
+		for f, field := range fields {
+			if !m.observed || stats[f].Count > 0 {
+				writeSample(w, c.name+m.suffix, c.fields[field], m.value(stats[f]))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkLabels panics if any field uses a reserved label or if two fields have the same labels, as either would give
+// invalid output
+func checkLabels(name string, fields map[string]Labels, reserved ...string) {
+	sorted := slices.Sorted(maps.Keys(fields))
+
+	for i, field := range sorted {
+		for _, r := range reserved {
+			if _, ok := fields[field][r]; ok {
+				panic(fmt.Sprintf("field %s of %s uses reserved label %s", field, name, r))
+			}
+		}
+		for _, other := range sorted[:i] {
+			if maps.Equal(fields[field], fields[other]) {
+				panic(fmt.Sprintf("fields %s and %s of %s have the same labels", other, field, name))
+			}
+		}
+	}
+}
+
+func writeHeader(w *bytes.Buffer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+}
+
+func writeSample(w *bytes.Buffer, name string, labels Labels, value string) {
+	w.WriteString(name)
+
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, n := range slices.Sorted(maps.Keys(labels)) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, n, escapeLabelValue(labels[n]))
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
@@ -0,0 +1,118 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/nyaruka/vkutil/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	clock := assertvk.NewFakeClock(time.Date(2021, 11, 18, 12, 7, 3, 0, time.UTC))
+
+	series := vkutil.NewIntervalSeries("msgs", time.Hour*24, 2, vkutil.WithClock(clock))
+	series.Record(ctx, vc, "sent", 5)
+	series.Record(ctx, vc, "failed", 2)
+
+	gauge := vkutil.NewIntervalGauge("queues", time.Hour*24, 2, vkutil.WithClock(clock))
+	gauge.Observe(ctx, vc, "handler", 12)
+	gauge.Observe(ctx, vc, "handler", 4.5)
+
+	exporter := metrics.NewExporter(vp)
+	exporter.RegisterSeries("msgs", "Number of messages", series, map[string]metrics.Labels{
+		"sent":   {"status": "sent"},
+		"failed": {"status": "failed", "reason": `bad "thing"`},
+	})
+	exporter.RegisterGauge("queue_size", "Size of queue", gauge, map[string]metrics.Labels{
+		"handler": {"queue": "handler"},
+		"batch":   nil,
+	})
+
+	b := &strings.Builder{}
+	require.NoError(t, exporter.Write(ctx, b))
+
+	expected := `# HELP msgs_window Number of messages (total across all intervals)
+# TYPE msgs_window gauge
+msgs_window{reason="bad \"thing\"",status="failed"} 2
+msgs_window{status="sent"} 5
+# HELP msgs Number of messages
+# TYPE msgs gauge
+msgs{interval="0",reason="bad \"thing\"",status="failed"} 2
+msgs{interval="1",reason="bad \"thing\"",status="failed"} 0
+msgs{interval="0",status="sent"} 5
+msgs{interval="1",status="sent"} 0
+# HELP queue_size_min Size of queue (minimum across all intervals)
+# TYPE queue_size_min gauge
+queue_size_min{queue="handler"} 4.5
+# HELP queue_size_max Size of queue (maximum across all intervals)
+# TYPE queue_size_max gauge
+queue_size_max{queue="handler"} 12
+# HELP queue_size_last Size of queue (last across all intervals)
+# TYPE queue_size_last gauge
+queue_size_last{queue="handler"} 4.5
+# HELP queue_size_observations Size of queue (number of observations across all intervals)
+# TYPE queue_size_observations gauge
+queue_size_observations 0
+queue_size_observations{queue="handler"} 2
+`
+	assert.Equal(t, expected, b.String())
+
+	// check we can also be used as an HTTP handler
+	rr := httptest.NewRecorder()
+	exporter.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, expected, rr.Body.String())
+
+	// if any metric can't be read, nothing is written
+	vc.Do("SET", "{errs}:2021-11-18", "x")
+
+	errSeries := vkutil.NewIntervalSeries("errs", time.Hour*24, 2, vkutil.WithClock(clock))
+	exporter.RegisterSeries("errs", "Number of errors", errSeries, map[string]metrics.Labels{"x": nil})
+
+	b.Reset()
+	assert.ErrorContains(t, exporter.Write(ctx, b), "error getting values for series errs")
+	assert.Equal(t, "", b.String())
+
+	rr = httptest.NewRecorder()
+	exporter.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "msgs")
+	assert.Contains(t, rr.Body.String(), "error writing metrics: error getting values for series errs")
+}
+
+func TestExporterInvalidLabels(t *testing.T) {
+	exporter := metrics.NewExporter(nil)
+	series := vkutil.NewIntervalSeries("msgs", time.Hour*24, 2)
+	gauge := vkutil.NewIntervalGauge("queues", time.Hour*24, 2)
+
+	assert.PanicsWithValue(t, "field sent of msgs uses reserved label interval", func() {
+		exporter.RegisterSeries("msgs", "Number of messages", series, map[string]metrics.Labels{"sent": {"interval": "x"}})
+	})
+	assert.PanicsWithValue(t, "fields failed and sent of msgs have the same labels", func() {
+		exporter.RegisterSeries("msgs", "Number of messages", series, map[string]metrics.Labels{"sent": {"status": "x"}, "failed": {"status": "x"}})
+	})
+	assert.PanicsWithValue(t, "fields batch and handler of queue_size have the same labels", func() {
+		exporter.RegisterGauge("queue_size", "Size of queue", gauge, map[string]metrics.Labels{"handler": {}, "batch": nil})
+	})
+	assert.NotPanics(t, func() {
+		exporter.RegisterGauge("queue_size", "Size of queue", gauge, map[string]metrics.Labels{"handler": {"queue": "handler"}, "batch": nil, "interval": {"interval": "x"}})
+	})
+}
@@ -0,0 +1,55 @@
+package vkutil
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+//go:embed lua/ihash_migrate.lua
+var ihashMigrate string
+var ihashMigrateScript = valkey.NewScript(-1, ihashMigrate)
+
+// Migrate copies the values of an interval hash into the new interval containing the end of each old interval, with
+// newer values winning and keeping remaining TTLs. Both hashes should use the same key base to support cluster mode.
+func Migrate(ctx context.Context, vc valkey.Conn, from, to *IntervalHash) error {
+	// each hash's keys are based on its own clock, but old intervals are mapped to new intervals based on the new clock
+	now, oldNow := to.clock.Now(), from.clock.Now()
+	oldKeys := from.keysAt(oldNow)
+	newKeys := to.keysAt(now)
+	oldCurr := from.wallStart(oldNow)
+	newCurr := to.wallStart(now)
+
+	// group old keys by the new key they'll be copied into, oldest first
+	sources := make(map[string][]string, len(newKeys))
+	for i := len(oldKeys) - 1; i >= 0; i-- {
+		oldEnd := from.fromWall(oldCurr.Add(from.interval * time.Duration(1-i))).Add(-time.Second)
+		if oldEnd.After(now) {
+			oldEnd = now
+		}
+
+		// find the new interval which contains the end of the old interval
+		n := int(newCurr.Sub(to.wallStart(oldEnd)) / to.interval)
+		if n >= len(newKeys) {
+			continue // too old to be visible in the new hash
+		}
+
+		sources[newKeys[n]] = append(sources[newKeys[n]], oldKeys[i])
+	}
+
+	for _, newKey := range newKeys {
+		srcs := sources[newKey]
+		if len(srcs) == 0 || (len(srcs) == 1 && srcs[0] == newKey) {
+			continue
+		}
+
+		if _, err := ihashMigrateScript.DoContext(ctx, vc, valkey.Args{}.Add(len(srcs)+1, newKey).AddFlat(srcs)...); err != nil {
+			return fmt.Errorf("error migrating %v to %s: %w", srcs, newKey, err)
+		}
+	}
+
+	return nil
+}
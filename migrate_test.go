@@ -0,0 +1,111 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	old := vkutil.NewIntervalHash("foos", time.Hour, 3)
+
+	setNow(time.Date(2021, 11, 18, 10, 30, 0, 0, time.UTC))
+	old.Set(ctx, vc, "A", "1")
+	old.Set(ctx, vc, "B", "1")
+
+	setNow(time.Date(2021, 11, 18, 11, 30, 0, 0, time.UTC))
+	old.Set(ctx, vc, "A", "2")
+
+	setNow(time.Date(2021, 11, 18, 12, 10, 0, 0, time.UTC))
+	old.Set(ctx, vc, "C", "3")
+
+	// writers switch to the new layout and write a newer value for A
+	setNow(time.Date(2021, 11, 18, 12, 20, 0, 0, time.UTC))
+
+	newHash := vkutil.NewIntervalHash("foos", time.Minute*15, 8)
+	newHash.Set(ctx, vc, "A", "9")
+
+	require.NoError(t, vkutil.Migrate(ctx, vc, old, newHash))
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:15", map[string]string{"A": "9", "C": "3"})
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T11:45", map[string]string{"A": "2"})
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T10:45", map[string]string{"A": "1", "B": "1"})
+
+	// migrated keys keep the remaining TTLs of the old keys
+	ttl, err := valkey.Int(vc.Do("TTL", "{foos}:2021-11-18T10:45"))
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 10700)
+
+	vals, err := newHash.MGet(ctx, vc, "A", "B", "C", "D")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"9", "1", "3", ""}, vals)
+
+	// migrating again is harmless
+	require.NoError(t, vkutil.Migrate(ctx, vc, old, newHash))
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:15", map[string]string{"A": "9", "C": "3"})
+}
+
+func TestMigrateToCoarser(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	old := vkutil.NewIntervalHash("foos", time.Minute*15, 8)
+
+	setNow(time.Date(2021, 11, 18, 11, 40, 0, 0, time.UTC))
+	old.Set(ctx, vc, "A", "0")
+
+	setNow(time.Date(2021, 11, 18, 12, 5, 0, 0, time.UTC))
+	old.Set(ctx, vc, "A", "1")
+	old.Set(ctx, vc, "B", "1")
+
+	setNow(time.Date(2021, 11, 18, 12, 20, 0, 0, time.UTC))
+	old.Set(ctx, vc, "A", "2")
+
+	setNow(time.Date(2021, 11, 18, 12, 35, 0, 0, time.UTC))
+	old.Set(ctx, vc, "C", "3")
+
+	// writers switch to the new layout, whose current key has the same name as the oldest quarter of this hour
+	setNow(time.Date(2021, 11, 18, 12, 50, 0, 0, time.UTC))
+
+	newHash := vkutil.NewIntervalHash("foos", time.Hour, 3)
+	newHash.Set(ctx, vc, "D", "4")
+
+	require.NoError(t, vkutil.Migrate(ctx, vc, old, newHash))
+
+	// values from later quarters overwrite values from earlier quarters
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:00", map[string]string{"A": "2", "B": "1", "C": "3", "D": "4"})
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T11:00", map[string]string{"A": "0"})
+
+	vals, err := newHash.MGet(ctx, vc, "A", "B", "C", "D")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "1", "3", "4"}, vals)
+
+	// migrating again is harmless
+	require.NoError(t, vkutil.Migrate(ctx, vc, old, newHash))
+
+	assertvk.HGetAll(t, vc, "{foos}:2021-11-18T12:00", map[string]string{"A": "2", "B": "1", "C": "3", "D": "4"})
+}
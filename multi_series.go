@@ -0,0 +1,76 @@
+package vkutil
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// MultiResolutionSeries records values into several interval series of different resolutions at once, e.g. per
+// minute for the last hour and per day for the last month.
+type MultiResolutionSeries struct {
+	series []*IntervalSeries // ordered from finest to coarsest
+}
+
+// NewMultiResolutionSeries creates a new multi-resolution series from the given series, which should each have a
+// different key base. A series of N intervals is only used for periods of up to N-1 intervals, as its oldest interval
+// has already partly expired.
+func NewMultiResolutionSeries(series ...*IntervalSeries) *MultiResolutionSeries {
+	series = slices.Clone(series)
+	slices.SortFunc(series, func(a, b *IntervalSeries) int { return cmp.Compare(a.interval, b.interval) })
+
+	return &MultiResolutionSeries{series: series}
+}
+
+// Record increments the value of field by value in the current interval of every resolution, in a single transaction.
+// To support cluster mode, the keys of all series must share a hashtag, e.g. by using a custom KeyFormatter.
+func (m *MultiResolutionSeries) Record(ctx context.Context, vc valkey.Conn, field string, value int64) error {
+	vc.Send("MULTI")
+	for _, s := range m.series {
+		currKey := s.keys()[0]
+
+		vc.Send("HINCRBY", currKey, field, value)
+		vc.Send("EXPIRE", currKey, s.size*int(s.interval/time.Second))
+	}
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// Total gets the total value of field over the given period, using the finest resolution whose intervals cover that
+// period. The total includes the whole of each interval which overlaps the period.
+func (m *MultiResolutionSeries) Total(ctx context.Context, vc valkey.Conn, field string, since time.Duration) (int64, error) {
+	s := m.Resolution(since)
+	if s == nil {
+		return 0, fmt.Errorf("no resolution covers a period of %s", since)
+	}
+
+	now := s.clock.Now()
+
+	vals, err := s.getAt(ctx, vc, field, now)
+	if err != nil {
+		return 0, err
+	}
+
+	overlapping := int(s.wallStart(now).Sub(s.wallStart(now.Add(-since)))/s.interval) + 1
+
+	var total int64
+	for _, v := range vals[:min(overlapping, len(vals))] {
+		total += v
+	}
+	return total, nil
+}
+
+// Resolution returns the finest series whose intervals cover the given period, or nil if there isn't one. Because the
+// period can start part way through an interval, a series covers it if all but its oldest interval do.
+func (m *MultiResolutionSeries) Resolution(since time.Duration) *IntervalSeries {
+	for _, s := range m.series {
+		if s.interval*time.Duration(s.size-1) >= since {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiResolutionSeries(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	defer vkutil.SetNow(time.Now)
+	setNow := func(d time.Time) { vkutil.SetNow(func() time.Time { return d }) }
+
+	assertTotal := func(m *vkutil.MultiResolutionSeries, f string, since time.Duration, expected int64) {
+		actual, err := m.Total(ctx, vc, f, since)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "total mismatch for field %s since %s", f, since)
+	}
+
+	minutes := vkutil.NewIntervalSeries("foos:m", time.Minute, 60)
+	days := vkutil.NewIntervalSeries("foos:d", time.Hour*24, 30)
+	multi := vkutil.NewMultiResolutionSeries(days, minutes)
+
+	assert.Equal(t, minutes, multi.Resolution(time.Minute*10))
+	assert.Equal(t, minutes, multi.Resolution(time.Minute*59))
+	assert.Equal(t, days, multi.Resolution(time.Hour))
+	assert.Equal(t, days, multi.Resolution(time.Hour*2))
+	assert.Equal(t, days, multi.Resolution(time.Hour*24*29))
+	assert.Nil(t, multi.Resolution(time.Hour*24*30))
+
+	setNow(time.Date(2021, 11, 17, 9, 0, 3, 234567, time.UTC))
+
+	assert.NoError(t, multi.Record(ctx, vc, "A", 4))
+
+	setNow(time.Date(2021, 11, 18, 11, 31, 10, 234567, time.UTC))
+
+	assert.NoError(t, multi.Record(ctx, vc, "A", 7))
+
+	setNow(time.Date(2021, 11, 18, 12, 7, 3, 234567, time.UTC))
+
+	assert.NoError(t, multi.Record(ctx, vc, "A", 3))
+
+	setNow(time.Date(2021, 11, 18, 12, 30, 3, 234567, time.UTC))
+
+	assert.NoError(t, multi.Record(ctx, vc, "A", 2))
+
+	assertvk.HGetAll(t, vc, "{foos:m}:2021-11-18T12:30", map[string]string{"A": "2"})
+	assertvk.HGetAll(t, vc, "{foos:m}:2021-11-18T12:07", map[string]string{"A": "3"})
+	assertvk.HGetAll(t, vc, "{foos:m}:2021-11-18T11:31", map[string]string{"A": "7"})
+	assertvk.HGetAll(t, vc, "{foos:d}:2021-11-18", map[string]string{"A": "12"})
+	assertvk.HGetAll(t, vc, "{foos:d}:2021-11-17", map[string]string{"A": "4"})
+
+	assertTotal(multi, "A", time.Minute*10, 2)
+	assertTotal(multi, "A", time.Minute*30, 5)
+	assertTotal(multi, "A", time.Minute*59, 12) // includes the oldest minute which started before the period
+	assertTotal(multi, "A", time.Hour, 12)      // whole of today
+	assertTotal(multi, "A", time.Hour*2, 12)    // whole of today
+	assertTotal(multi, "A", time.Hour*24, 16)   // whole of today and yesterday
+	assertTotal(multi, "B", time.Minute*59, 0)
+
+	_, err := multi.Total(ctx, vc, "A", time.Hour*24*60)
+	assert.EqualError(t, err, "no resolution covers a period of 1440h0m0s")
+
+	// check the clock is only read once, even if time crosses an interval boundary during the call
+	minutes2 := vkutil.NewIntervalSeries("bars:m", time.Minute, 60)
+	multi2 := vkutil.NewMultiResolutionSeries(minutes2)
+
+	setNow(time.Date(2021, 11, 18, 12, 29, 10, 0, time.UTC))
+	assert.NoError(t, multi2.Record(ctx, vc, "A", 100))
+
+	setNow(time.Date(2021, 11, 18, 12, 35, 10, 0, time.UTC))
+	assert.NoError(t, multi2.Record(ctx, vc, "A", 1))
+
+	calls := 0
+	vkutil.SetNow(func() time.Time {
+		calls++
+		if calls == 1 {
+			return time.Date(2021, 11, 18, 12, 39, 59, 0, time.UTC)
+		}
+		return time.Date(2021, 11, 18, 12, 40, 0, 0, time.UTC)
+	})
+
+	assertTotal(multi2, "A", time.Minute*9+time.Second*30, 1) // period starts at 12:30:29 so 12:29 isn't included
+}
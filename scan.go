@@ -0,0 +1,77 @@
+package vkutil
+
+import (
+	"context"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// ScanOption configures a scan over an interval based struct
+type ScanOption func(*scanOptions)
+
+type scanOptions struct {
+	match string
+	count int
+}
+
+// WithMatch configures a MATCH pattern to filter scanned members or fields
+func WithMatch(pattern string) ScanOption {
+	return func(o *scanOptions) { o.match = pattern }
+}
+
+// WithCount configures the COUNT hint, i.e. how many elements are fetched per call
+func WithCount(count int) ScanOption {
+	return func(o *scanOptions) { o.count = count }
+}
+
+func newScanOptions(opts []ScanOption) *scanOptions {
+	o := &scanOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// scanKeys iterates over the given keys in order using a cursor based scan command (e.g. SSCAN, HSCAN), passing each
+// batch of returned items to fn along with the index of the key they came from. Iteration stops early if fn returns
+// false or an error.
+func scanKeys(ctx context.Context, vc valkey.Conn, cmd string, keys []string, o *scanOptions, fn func(int, []string) (bool, error)) error {
+	for i, key := range keys {
+		cursor := "0"
+
+		for {
+			args := valkey.Args{}.Add(key, cursor)
+			if o.match != "" {
+				args = args.Add("MATCH", o.match)
+			}
+			if o.count > 0 {
+				args = args.Add("COUNT", o.count)
+			}
+
+			reply, err := valkey.Values(valkey.DoContext(vc, ctx, cmd, args...))
+			if err != nil {
+				return err
+			}
+
+			cursor, err = valkey.String(reply[0], nil)
+			if err != nil {
+				return err
+			}
+			items, err := valkey.Strings(reply[1], nil)
+			if err != nil {
+				return err
+			}
+
+			if len(items) > 0 {
+				more, err := fn(i, items)
+				if err != nil || !more {
+					return err
+				}
+			}
+			if cursor == "0" {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package vkutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// ServerClock is a clock which follows the time of the server rather than the local time, so that clients with skewed
+// clocks still agree on intervals. It maintains a cached offset from the local time which should be synced
+// periodically using Sync or StartSyncing.
+type ServerClock struct {
+	vp     *valkey.Pool
+	local  Clock
+	mutex  sync.RWMutex
+	offset time.Duration
+}
+
+// ServerClockOption is an option for a server clock
+type ServerClockOption func(*ServerClock)
+
+// WithLocalClock configures the source of the local time which the server offset is applied to, e.g. to control time
+// in tests
+func WithLocalClock(local Clock) ServerClockOption {
+	return func(c *ServerClock) { c.local = local }
+}
+
+// NewServerClock creates a new server clock which will use the given pool to query server time. Until synced, it
+// returns the local time.
+func NewServerClock(vp *valkey.Pool, opts ...ServerClockOption) *ServerClock {
+	c := &ServerClock{vp: vp, local: systemClock{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Now returns the current server time, based on the local time and the last synced offset
+func (c *ServerClock) Now() time.Time {
+	return c.local.Now().Add(c.Offset())
+}
+
+// Offset returns the last synced offset of server time from local time
+func (c *ServerClock) Offset() time.Duration {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.offset
+}
+
+// Sync queries the server time and updates the offset, compensating for the round trip time
+func (c *ServerClock) Sync(ctx context.Context) error {
+	vc := c.vp.Get()
+	defer vc.Close()
+
+	before := c.local.Now()
+	reply, err := valkey.Int64s(valkey.DoContext(vc, ctx, "TIME"))
+	after := c.local.Now()
+
+	if err != nil {
+		return fmt.Errorf("error getting server time: %w", err)
+	}
+
+	server := time.Unix(reply[0], reply[1]*int64(time.Microsecond))
+	local := before.Add(after.Sub(before) / 2)
+
+	c.mutex.Lock()
+	c.offset = server.Sub(local)
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// StartSyncing starts a background goroutine which syncs the offset at the given frequency until the context is
+// cancelled. If onError is not nil, it's called with any errors from syncing. The returned channel is closed when the
+// goroutine exits.
+func (c *ServerClock) StartSyncing(ctx context.Context, every time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Sync(ctx); err != nil && onError != nil && ctx.Err() == nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	return done
+}
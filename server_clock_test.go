@@ -0,0 +1,50 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerClock(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	// pretend our local clock is an hour behind
+	local := assertvk.NewFakeClock(time.Now().Add(-time.Hour))
+
+	clock := vkutil.NewServerClock(vp, vkutil.WithLocalClock(local))
+	assert.Equal(t, time.Duration(0), clock.Offset())
+	assert.Equal(t, local.Now(), clock.Now())
+
+	require.NoError(t, clock.Sync(ctx))
+
+	assert.InDelta(t, float64(time.Hour), float64(clock.Offset()), float64(time.Second))
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+
+	// interval keys are now based on server time
+	set := vkutil.NewIntervalSet("foos", time.Hour*24, 2, vkutil.WithClock(clock))
+	set.Add(ctx, vc, "A")
+
+	assertvk.SMembers(t, vc, "{foos}:"+clock.Now().UTC().Format("2006-01-02"), []string{"A"})
+
+	// check background syncing
+	clock2 := vkutil.NewServerClock(vp, vkutil.WithLocalClock(assertvk.NewFakeClock(time.Now().Add(-time.Hour))))
+
+	syncCtx, cancel := context.WithCancel(ctx)
+	done := clock2.StartSyncing(syncCtx, time.Millisecond*10, func(err error) { assert.NoError(t, err) })
+
+	assert.Eventually(t, func() bool { return clock2.Offset() > time.Minute }, time.Second, time.Millisecond*10)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,132 @@
+package vkutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	valkey "github.com/gomodule/redigo/redis"
+)
+
+// SlidingSet is a set where members expire precisely after a window, backed by a sorted set scored by the time each
+// member was added. Expired members are trimmed lazily on writes, or by an optional background trimmer.
+type SlidingSet struct {
+	key    string
+	window time.Duration
+	clock  Clock
+}
+
+// SlidingSetOption is an option for a sliding set
+type SlidingSetOption func(*SlidingSet)
+
+// WithSlidingClock configures the source of the current time for a sliding set, e.g. to control time in tests
+func WithSlidingClock(c Clock) SlidingSetOption {
+	return func(s *SlidingSet) { s.clock = c }
+}
+
+// NewSlidingSet creates a new sliding set, panicking if the window is less than a millisecond
+func NewSlidingSet(key string, window time.Duration, opts ...SlidingSetOption) *SlidingSet {
+	if window < time.Millisecond {
+		panic(fmt.Sprintf("window must be at least 1ms, got %s", window))
+	}
+
+	s := &SlidingSet{key: key, window: window, clock: systemClock{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds the given values, or updates their times if they're already members
+func (s *SlidingSet) Add(ctx context.Context, vc valkey.Conn, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+	score := now.UnixMilli()
+	args := valkey.Args{}.Add(s.key)
+	for _, m := range members {
+		args = args.Add(score, m)
+	}
+
+	vc.Send("MULTI")
+	vc.Send("ZADD", args...)
+	vc.Send("ZREMRANGEBYSCORE", s.key, "-inf", s.minScore(now))
+	vc.Send("PEXPIRE", s.key, s.window.Milliseconds())
+	_, err := valkey.DoContext(vc, ctx, "EXEC")
+	return err
+}
+
+// IsMember returns whether the given value was added within the window
+func (s *SlidingSet) IsMember(ctx context.Context, vc valkey.Conn, member string) (bool, error) {
+	score, err := valkey.Int64(valkey.DoContext(vc, ctx, "ZSCORE", s.key, member))
+	if err == valkey.ErrNil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return score > s.minScore(s.clock.Now()), nil
+}
+
+// Card returns the number of values added within the window
+func (s *SlidingSet) Card(ctx context.Context, vc valkey.Conn) (int, error) {
+	return valkey.Int(valkey.DoContext(vc, ctx, "ZCOUNT", s.key, fmt.Sprintf("(%d", s.minScore(s.clock.Now())), "+inf"))
+}
+
+// Members returns all values added within the window, ordered from oldest to newest
+func (s *SlidingSet) Members(ctx context.Context, vc valkey.Conn) ([]string, error) {
+	return valkey.Strings(valkey.DoContext(vc, ctx, "ZRANGE", s.key, fmt.Sprintf("(%d", s.minScore(s.clock.Now())), "+inf", "BYSCORE"))
+}
+
+// Rem removes the given values
+func (s *SlidingSet) Rem(ctx context.Context, vc valkey.Conn, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	_, err := valkey.DoContext(vc, ctx, "ZREM", valkey.Args{}.Add(s.key).AddFlat(members)...)
+	return err
+}
+
+// Trim removes values which were added before the window, returning the number removed
+func (s *SlidingSet) Trim(ctx context.Context, vc valkey.Conn) (int, error) {
+	return valkey.Int(valkey.DoContext(vc, ctx, "ZREMRANGEBYSCORE", s.key, "-inf", s.minScore(s.clock.Now())))
+}
+
+// StartTrimmer starts a background goroutine which trims expired values at the given frequency until the context is
+// cancelled. If onError is not nil, it's called with any errors from trimming. The returned channel is closed when the
+// goroutine exits.
+func (s *SlidingSet) StartTrimmer(ctx context.Context, vp *valkey.Pool, every time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				vc := vp.Get()
+				_, err := s.Trim(ctx, vc)
+				vc.Close()
+
+				if err != nil && onError != nil && ctx.Err() == nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// minScore returns the score at or below which values are outside of the window
+func (s *SlidingSet) minScore(now time.Time) int64 {
+	return now.Add(-s.window).UnixMilli()
+}
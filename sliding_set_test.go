@@ -0,0 +1,110 @@
+package vkutil_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/vkutil"
+	"github.com/nyaruka/vkutil/assertvk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingSet(t *testing.T) {
+	ctx := context.Background()
+	vp := assertvk.TestDB()
+	vc := vp.Get()
+	defer vc.Close()
+
+	defer assertvk.FlushDB()
+
+	assertIsMember := func(s *vkutil.SlidingSet, member string, expected bool) {
+		actual, err := s.IsMember(ctx, vc, member)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, "is member mismatch for %s", member)
+	}
+	assertMembers := func(s *vkutil.SlidingSet, expected []string) {
+		actual, err := s.Members(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+
+		card, err := s.Card(ctx, vc)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), card)
+	}
+
+	t0 := time.Date(2021, 11, 18, 12, 0, 0, 0, time.UTC)
+	clock := assertvk.NewFakeClock(t0)
+
+	// create a set with a 10 minute window
+	set := vkutil.NewSlidingSet("foos", time.Minute*10, vkutil.WithSlidingClock(clock))
+	assertIsMember(set, "A", false)
+	assertMembers(set, []string{})
+
+	assert.NoError(t, set.Add(ctx, vc, "A"))
+
+	clock.Set(t0.Add(time.Minute * 4))
+	assert.NoError(t, set.Add(ctx, vc, "B", "C"))
+	assert.NoError(t, set.Add(ctx, vc)) // noop
+
+	assertvk.ZGetAll(t, vc, "foos", map[string]float64{"A": 1637236800000, "B": 1637237040000, "C": 1637237040000})
+
+	assertIsMember(set, "A", true)
+	assertIsMember(set, "B", true)
+	assertIsMember(set, "D", false)
+	assertMembers(set, []string{"A", "B", "C"})
+
+	// A expires exactly 10 minutes after it was added
+	clock.Set(t0.Add(time.Minute*10 - time.Millisecond))
+	assertIsMember(set, "A", true)
+
+	clock.Set(t0.Add(time.Minute * 10))
+	assertIsMember(set, "A", false)
+	assertMembers(set, []string{"B", "C"})
+
+	// but isn't removed until the next write or trim
+	assertvk.ZCard(t, vc, "foos", 3)
+
+	assert.NoError(t, set.Add(ctx, vc, "C")) // re-adding updates time
+
+	assertvk.ZGetAll(t, vc, "foos", map[string]float64{"B": 1637237040000, "C": 1637237400000})
+
+	clock.Set(t0.Add(time.Minute * 14))
+
+	assertMembers(set, []string{"C"})
+
+	removed, err := set.Trim(ctx, vc)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	assertvk.ZGetAll(t, vc, "foos", map[string]float64{"C": 1637237400000})
+
+	assert.NoError(t, set.Rem(ctx, vc, "C"))
+	assert.NoError(t, set.Rem(ctx, vc)) // noop
+
+	assertMembers(set, []string{})
+
+	// check background trimming
+	clock.Set(t0)
+	set.Add(ctx, vc, "A", "B")
+
+	clock.Set(t0.Add(time.Minute * 11))
+
+	trimCtx, cancel := context.WithCancel(ctx)
+	done := set.StartTrimmer(trimCtx, vp, time.Millisecond*10, func(err error) { assert.NoError(t, err) })
+
+	assert.Eventually(t, func() bool {
+		card, _ := set.Card(ctx, vc)
+		exists, _ := vc.Do("EXISTS", "foos")
+		return card == 0 && exists == int64(0)
+	}, time.Second, time.Millisecond*10)
+
+	cancel()
+	<-done
+}
+
+func TestSlidingSetInvalid(t *testing.T) {
+	assert.PanicsWithValue(t, "window must be at least 1ms, got 0s", func() { vkutil.NewSlidingSet("foos", 0) })
+	assert.PanicsWithValue(t, "window must be at least 1ms, got 500µs", func() { vkutil.NewSlidingSet("foos", time.Microsecond*500) })
+	assert.PanicsWithValue(t, "window must be at least 1ms, got -1m0s", func() { vkutil.NewSlidingSet("foos", -time.Minute) })
+}
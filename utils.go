@@ -1,7 +1,6 @@
 package vkutil
 
 import (
-	"fmt"
 	"math/rand/v2"
 	"strconv"
 	"time"
@@ -38,28 +37,6 @@ func StringsWithScores(reply any, err error) ([]string, []float64, error) {
 	return strings, scores, nil
 }
 
-func intervalTimestamp(t time.Time, interval time.Duration) string {
-	t = t.UTC().Truncate(interval)
-
-	if interval < time.Minute {
-		return t.Format("2006-01-02T15:04:05")
-	}
-	if interval < time.Hour*24 {
-		return t.Format("2006-01-02T15:04")
-	}
-	return t.Format("2006-01-02")
-}
-
-func intervalKeys(keyBase string, interval time.Duration, size int) []string {
-	now := timeNow()
-	keys := make([]string, size)
-	for i := range keys {
-		timestamp := intervalTimestamp(now.Add(-interval*time.Duration(i)), interval)
-		keys[i] = fmt.Sprintf("{%s}:%s", keyBase, timestamp)
-	}
-	return keys
-}
-
 const base64Charset = `ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/`
 
 // RandomBase64 creates a random string of the length passed in